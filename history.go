@@ -0,0 +1,38 @@
+package main
+
+// historyBufferSize คือจำนวน snapshot/chat ล่าสุดที่เก็บไว้ต่อห้อง สำหรับ replay ตอน reconnect
+const historyBufferSize = 50
+
+// HistoryEntry คือข้อความขาออกหนึ่งชิ้น (room snapshot หรือ chat) ที่ถูกแปะ sequence number
+// เรียงตามลำดับเวลา ใช้ตอน client reconnect แล้วขอ replay ทุกอย่างหลัง seq ที่เคยเห็น
+type HistoryEntry struct {
+	Seq     uint64
+	Payload interface{}
+}
+
+// pushHistory ต้องถูกเรียกตอนถือ room.mu อยู่แล้ว มันแปะ seq ใหม่แล้วเก็บลง ring buffer
+func pushHistory(room *Room, payload interface{}) uint64 {
+	room.seq++
+	entry := HistoryEntry{Seq: room.seq, Payload: payload}
+
+	room.history = append(room.history, entry)
+	if len(room.history) > historyBufferSize {
+		room.history = room.history[len(room.history)-historyBufferSize:]
+	}
+
+	return room.seq
+}
+
+// replayHistory ส่งทุก entry ที่ seq มากกว่า afterSeq กลับไปให้ player คนเดียว
+// ต้องถูกเรียกตอนถือ room.mu อยู่แล้ว
+func replayHistory(room *Room, player *Player, afterSeq uint64) {
+	if player.Conn == nil {
+		return
+	}
+	for _, entry := range room.history {
+		if entry.Seq <= afterSeq {
+			continue
+		}
+		_ = player.Conn.WriteJSON(entry.Payload)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+const (
+	// janitorInterval คือความถี่ที่ janitor goroutine สแกนห้องแบบอัตโนมัติ
+	janitorInterval = 3 * time.Minute
+	// roomIdleTTL คือเวลาที่ห้องไม่มีความเคลื่อนไหว (ดู Room.LastActivity) ก่อนจะถูกเก็บกวาด
+	roomIdleTTL = 15 * time.Minute
+
+	// maxRooms คือจำนวนห้อง active สูงสุดที่เซิร์ฟเวอร์รับพร้อมกัน ป้องกัน mode=create ถูกถล่ม
+	maxRooms = 500
+
+	createRateLimit  = 5
+	createRateWindow = 10 * time.Minute
+)
+
+// ErrTooManyRooms is returned by getOrCreateRoom once the server-wide maxRooms cap is hit.
+type ErrTooManyRooms struct{}
+
+func (ErrTooManyRooms) Error() string {
+	return "too many active rooms, try again later"
+}
+
+// doPrune triggers an out-of-band prune pass (e.g. right after hitting maxRooms) without
+// waiting for the next janitorInterval tick - the same "doPrune channel" pattern codies uses.
+var doPrune = make(chan struct{}, 1)
+
+var roomsPrunedTotal uint64
+
+// triggerPrune requests a prune pass as soon as the janitor goroutine next wakes up.
+// Non-blocking: if a prune is already queued, it's a no-op.
+func triggerPrune() {
+	select {
+	case doPrune <- struct{}{}:
+	default:
+	}
+}
+
+// startJanitor launches the background room-pruning loop. It wakes on a fixed interval or
+// on-demand via doPrune, whichever comes first.
+func startJanitor(interval, idleTTL time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				pruneRooms(idleTTL)
+			case <-doPrune:
+				pruneRooms(idleTTL)
+			}
+		}
+	}()
+}
+
+// pruneRooms evicts rooms that are empty or idle past idleTTL, closing any hanging
+// websocket connections first, then emits rooms_active/clients_active/rooms_pruned_total
+// so operators can watch load.
+func pruneRooms(idleTTL time.Duration) {
+	roomsMu.Lock()
+	codes := make([]string, 0, len(rooms))
+	for code := range rooms {
+		codes = append(codes, code)
+	}
+	roomsMu.Unlock()
+
+	now := time.Now()
+	pruned := 0
+
+	for _, code := range codes {
+		roomsMu.Lock()
+		room, ok := rooms[code]
+		roomsMu.Unlock()
+		if !ok {
+			continue
+		}
+
+		room.mu.Lock()
+		shouldPrune := len(room.Players) == 0 || now.Sub(room.LastActivity) > idleTTL
+		var hanging []*websocket.Conn
+		if shouldPrune {
+			for _, p := range room.Players {
+				if p.Conn != nil {
+					hanging = append(hanging, p.Conn)
+				}
+			}
+		}
+		room.mu.Unlock()
+
+		if !shouldPrune {
+			continue
+		}
+
+		for _, conn := range hanging {
+			_ = conn.Close()
+		}
+
+		roomsMu.Lock()
+		delete(rooms, code)
+		roomsMu.Unlock()
+
+		if store != nil {
+			if err := store.DeleteRoom(code); err != nil {
+				log.Println("[janitor] failed to delete room", code, "from store -", err)
+			}
+		}
+
+		pruned++
+	}
+
+	atomic.AddUint64(&roomsPrunedTotal, uint64(pruned))
+
+	roomsMu.Lock()
+	activeRooms := len(rooms)
+	clientsActive := 0
+	for _, room := range rooms {
+		room.mu.Lock()
+		for _, p := range room.Players {
+			if p.Conn != nil {
+				clientsActive++
+			}
+		}
+		room.mu.Unlock()
+	}
+	roomsMu.Unlock()
+
+	log.Printf("[janitor] rooms_active=%d clients_active=%d rooms_pruned=%d rooms_pruned_total=%d\n",
+		activeRooms, clientsActive, pruned, atomic.LoadUint64(&roomsPrunedTotal))
+}
+
+var (
+	createRateMu   sync.Mutex
+	createRateByIP = make(map[string][]time.Time)
+)
+
+// clientIP extracts the bare IP (no port) from a websocket connection's remote address.
+func clientIP(c *websocket.Conn) string {
+	addr := c.RemoteAddr()
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// allowRoomCreate enforces a per-IP sliding-window rate limit on mode=create so a single
+// client can't trivially DoS the server by spamming new rooms.
+func allowRoomCreate(ip string) bool {
+	if ip == "" {
+		return true
+	}
+
+	createRateMu.Lock()
+	defer createRateMu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-createRateWindow)
+
+	recent := createRateByIP[ip][:0]
+	for _, t := range createRateByIP[ip] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= createRateLimit {
+		createRateByIP[ip] = recent
+		return false
+	}
+
+	createRateByIP[ip] = append(recent, now)
+	return true
+}
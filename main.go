@@ -1,12 +1,12 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
+	"flag"
 	"log"
 	"math/rand"
 	"os"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -15,11 +15,14 @@ import (
 )
 
 type Player struct {
-	ID    string          `json:"id"`
-	Name  string          `json:"name"`
-	Score int             `json:"score"`
-	Role  string          `json:"role"`
-	Conn  *websocket.Conn `json:"-"`
+	ID           string          `json:"id"`
+	Name         string          `json:"name"`
+	Score        int             `json:"score"`
+	Role         string          `json:"role"`
+	Disconnected bool            `json:"disconnected,omitempty"`
+	Conn         *websocket.Conn `json:"-"`
+
+	graceCancel chan struct{}
 }
 
 type Room struct {
@@ -30,47 +33,62 @@ type Room struct {
 	InsiderID string `json:"insiderId"`
 	Timer     int    `json:"timer"`
 
-	SecretWord        string `json:"secretWord,omitempty"`
+	// SecretWord ไม่ถูกส่งออกไปใน snapshot เพราะเป็นข้อมูลลับ ส่งให้เฉพาะกรรมการ/Insider
+	// ผ่าน SecretWordMessage เท่านั้น (ดู sendSecretWord)
+	SecretWord        string `json:"-"`
+	Category          string `json:"category,omitempty"`
 	RoundEndByTimeout bool   `json:"roundEndByTimeout"`
 	ChatEnabled       bool   `json:"chatEnabled"`
 
-	BlockedVoters map[string]bool `json:"blockedVoters,omitempty"`
-	Voted         map[string]bool `json:"voted,omitempty"`
-	LastVotes     []VotePair      `json:"lastVotes,omitempty"`
+	// ActiveVote คือการโหวตที่กำลังดำเนินอยู่ (ถ้ามี) ไม่ว่าจะเป็น insider, kick, pause,
+	// change_judge หรือ extend_timer - ดู voting.go
+	ActiveVote      *Voting `json:"activeVote,omitempty"`
+	ExtendTimerUsed bool    `json:"-"`
+	Paused          bool    `json:"paused"`
 
 	Players map[string]*Player `json:"players"`
-	Votes   map[string]string  `json:"-"`
+
+	// UsedWords กันไม่ให้สุ่มคำซ้ำในห้องนี้ แยกเก็บเป็นรายหมวด (category -> คำที่ใช้ไปแล้ว)
+	UsedWords map[string]map[string]bool `json:"-"`
+	// CustomDecks คือ deck ที่ผู้เล่นอัปโหลดเอง ใช้ได้เฉพาะห้องนี้ (category -> deck)
+	CustomDecks map[string]*WordDeck `json:"-"`
 
 	timerRunning bool
 	timerCancel  chan struct{}
+	voteCancel   chan struct{}
 
-	mu sync.Mutex
-}
+	seq     uint64
+	history []HistoryEntry
+
+	// LastActivity อัปเดตทุกครั้งที่มีข้อความเข้า, broadcast, หรือ timer tick ใช้เป็นตัวตัดสิน
+	// idle TTL ของ janitor (ดู janitor.go)
+	LastActivity time.Time `json:"-"`
 
-type VotePair struct {
-	VoterID  string `json:"voterId"`
-	TargetID string `json:"targetId"`
+	mu sync.Mutex
 }
 
+// OutgoingRoomMessage is the params payload of a "room.snapshot" notification (see
+// broadcastRoom/sendRoomToPlayer in rpc.go's RPCNotification wrapper).
 type OutgoingRoomMessage struct {
-	Type   string `json:"type"`
 	SelfID string `json:"selfId,omitempty"`
 	Room   *Room  `json:"room"`
 }
 
-type ErrorMessage struct {
-	Type    string `json:"type"`
-	Message string `json:"message"`
+// SessionMessage is the params payload of a "room.session" notification, sent to a client
+// right after it joins. Kept for reconnect: the client holds onto Token and sends it back as
+// token= to rebind to its existing Player instead of being treated as a new one.
+type SessionMessage struct {
+	Token     string `json:"token"`
+	PlayerID  string `json:"playerId"`
+	LastSeq   uint64 `json:"lastSeq"`
+	ExpiresAt int64  `json:"expiresAt"`
 }
 
-type ClientMessage struct {
-	Type        string `json:"type"`
-	TargetID    string `json:"targetId,omitempty"`
-	Duration    int    `json:"duration,omitempty"`
-	SuspectID   string `json:"suspectId,omitempty"`
-	SecretWord  string `json:"secretWord,omitempty"`
-	Text        string `json:"text,omitempty"`
-	ChatEnabled *bool  `json:"chatEnabled,omitempty"`
+// SecretWordMessage is the params payload of a "room.secretWord" notification, sent
+// privately to only the judge and insider connections (broadcastRoom/sendRoomToPlayer never
+// include SecretWord in the room snapshot).
+type SecretWordMessage struct {
+	SecretWord string `json:"secretWord"`
 }
 
 type ChatFrom struct {
@@ -78,8 +96,8 @@ type ChatFrom struct {
 	Name string `json:"name"`
 }
 
+// ChatPayload is the params payload of a "chat.message" notification.
 type ChatPayload struct {
-	Type string   `json:"type"`
 	From ChatFrom `json:"from"`
 	Text string   `json:"text"`
 	Ts   int64    `json:"ts"`
@@ -88,6 +106,10 @@ type ChatPayload struct {
 const (
 	RoundDurationSeconds = 300
 	VoteDurationSeconds  = 90
+
+	// reconnectGraceSeconds คือเวลาที่ Player ที่หลุดการเชื่อมต่อจะยังถูกเก็บไว้ในห้อง
+	// (state: disconnected=true) ก่อนจะถูกลบจริง ๆ กันโหวต/role พังเพราะเน็ตสะดุด
+	reconnectGraceSeconds = 30
 )
 
 var (
@@ -95,37 +117,45 @@ var (
 	roomsMu sync.Mutex
 )
 
+var (
+	ErrRoomExists   = errors.New("room already exists")
+	ErrRoomNotFound = errors.New("room not found")
+)
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
-func getOrCreateRoom(code string, create bool) (*Room, bool) {
+func getOrCreateRoom(code string, create bool) (*Room, error) {
 	roomsMu.Lock()
 	defer roomsMu.Unlock()
 
 	if room, ok := rooms[code]; ok {
 		if create {
-			return nil, false
+			return nil, ErrRoomExists
 		}
-		return room, true
+		return room, nil
 	}
 
 	if !create {
-		return nil, false
+		return nil, ErrRoomNotFound
+	}
+
+	if len(rooms) >= maxRooms {
+		return nil, ErrTooManyRooms{}
 	}
 
 	room := &Room{
-		Code:          code,
-		State:         "lobby",
-		Players:       make(map[string]*Player),
-		Votes:         make(map[string]string),
-		BlockedVoters: make(map[string]bool),
-		Voted:         make(map[string]bool),
-		LastVotes:     []VotePair{},
-		ChatEnabled:   true,
+		Code:         code,
+		State:        "lobby",
+		Players:      make(map[string]*Player),
+		ChatEnabled:  true,
+		UsedWords:    make(map[string]map[string]bool),
+		CustomDecks:  make(map[string]*WordDeck),
+		LastActivity: time.Now(),
 	}
 	rooms[code] = room
-	return room, true
+	return room, nil
 }
 
 func deleteRoomIfEmpty(room *Room) {
@@ -134,6 +164,11 @@ func deleteRoomIfEmpty(room *Room) {
 
 	if len(room.Players) == 0 {
 		delete(rooms, room.Code)
+		if store != nil {
+			if err := store.DeleteRoom(room.Code); err != nil {
+				log.Println("[store] failed to delete room", room.Code, "-", err)
+			}
+		}
 	}
 }
 
@@ -145,6 +180,8 @@ func broadcastRoom(room *Room) {
 	room.mu.Lock()
 	defer room.mu.Unlock()
 
+	room.LastActivity = time.Now()
+
 	snap := &Room{
 		Code:              room.Code,
 		State:             room.State,
@@ -156,37 +193,34 @@ func broadcastRoom(room *Room) {
 		RoundEndByTimeout: room.RoundEndByTimeout,
 		ChatEnabled:       room.ChatEnabled,
 
-		BlockedVoters: make(map[string]bool),
-		Voted:         make(map[string]bool),
-		LastVotes:     append([]VotePair(nil), room.LastVotes...),
-		Players:       make(map[string]*Player),
-	}
-
-	for id, b := range room.BlockedVoters {
-		snap.BlockedVoters[id] = b
-	}
-	for id, v := range room.Voted {
-		snap.Voted[id] = v
+		ActiveVote: room.ActiveVote,
+		Paused:     room.Paused,
+		Players:    make(map[string]*Player),
 	}
 
 	for id, p := range room.Players {
 		snap.Players[id] = &Player{
-			ID:    p.ID,
-			Name:  p.Name,
-			Score: p.Score,
-			Role:  p.Role,
+			ID:           p.ID,
+			Name:         p.Name,
+			Score:        p.Score,
+			Role:         p.Role,
+			Disconnected: p.Disconnected,
 		}
 	}
 
+	notification := RPCNotification{
+		JSONRPC: "2.0",
+		Method:  "room.snapshot",
+		Params:  OutgoingRoomMessage{Room: snap},
+	}
+	pushHistory(room, notification)
+	persistRoomLocked(room)
+
 	for _, p := range room.Players {
 		if p.Conn == nil {
 			continue
 		}
-		msg := OutgoingRoomMessage{
-			Type: "room",
-			Room: snap,
-		}
-		_ = p.Conn.WriteJSON(msg)
+		_ = p.Conn.WriteJSON(notification)
 	}
 }
 
@@ -205,41 +239,36 @@ func sendRoomToPlayer(room *Room, player *Player) {
 		RoundEndByTimeout: room.RoundEndByTimeout,
 		ChatEnabled:       room.ChatEnabled,
 
-		BlockedVoters: make(map[string]bool),
-		Voted:         make(map[string]bool),
-		LastVotes:     append([]VotePair(nil), room.LastVotes...),
-		Players:       make(map[string]*Player),
-	}
-
-	for id, b := range room.BlockedVoters {
-		snap.BlockedVoters[id] = b
-	}
-	for id, v := range room.Voted {
-		snap.Voted[id] = v
+		ActiveVote: room.ActiveVote,
+		Paused:     room.Paused,
+		Players:    make(map[string]*Player),
 	}
 
 	for id, p := range room.Players {
 		snap.Players[id] = &Player{
-			ID:    p.ID,
-			Name:  p.Name,
-			Score: p.Score,
-			Role:  p.Role,
+			ID:           p.ID,
+			Name:         p.Name,
+			Score:        p.Score,
+			Role:         p.Role,
+			Disconnected: p.Disconnected,
 		}
 	}
 
-	msg := OutgoingRoomMessage{
-		Type:   "room",
-		SelfID: player.ID,
-		Room:   snap,
-	}
-	_ = player.Conn.WriteJSON(msg)
+	notify(player.Conn, "room.snapshot", OutgoingRoomMessage{SelfID: player.ID, Room: snap})
 }
 
-func sendError(conn *websocket.Conn, text string) {
-	_ = conn.WriteJSON(ErrorMessage{
-		Type:    "error",
-		Message: text,
-	})
+// sendSecretWord ส่งคำปริศนาแบบ private ให้เฉพาะกรรมการกับ Insider เท่านั้น
+// ต้องเรียกหลัง assignRoles เพื่อให้ room.InsiderID ถูกกำหนดแล้ว
+func sendSecretWord(room *Room) {
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	params := SecretWordMessage{SecretWord: room.SecretWord}
+	for _, id := range []string{room.JudgeID, room.InsiderID} {
+		if p, ok := room.Players[id]; ok && p.Conn != nil {
+			notify(p.Conn, "room.secretWord", params)
+		}
+	}
 }
 
 func assignRoles(room *Room) {
@@ -285,9 +314,12 @@ func startCountdownTimer(room *Room, duration int) {
 	room.timerCancel = make(chan struct{})
 
 	room.RoundEndByTimeout = false
-	room.BlockedVoters = make(map[string]bool)
-	room.Voted = make(map[string]bool)
-	room.LastVotes = []VotePair{}
+	room.ActiveVote = nil
+	room.ExtendTimerUsed = false
+	if room.voteCancel != nil {
+		close(room.voteCancel)
+		room.voteCancel = nil
+	}
 
 	cancelChan := room.timerCancel
 	room.mu.Unlock()
@@ -304,6 +336,10 @@ func startCountdownTimer(room *Room, duration int) {
 					r.mu.Unlock()
 					return
 				}
+				if r.Paused {
+					r.mu.Unlock()
+					continue
+				}
 				if r.Timer > 0 {
 					r.Timer--
 				}
@@ -313,7 +349,7 @@ func startCountdownTimer(room *Room, duration int) {
 					r.timerRunning = false
 					r.State = "scoreboard"
 					r.RoundEndByTimeout = true
-					r.Votes = make(map[string]string)
+					r.ActiveVote = nil
 					r.mu.Unlock()
 					broadcastRoom(r)
 					return
@@ -351,14 +387,23 @@ func startVoteTimer(room *Room, duration int) {
 					r.mu.Unlock()
 					return
 				}
+				if r.Paused {
+					r.mu.Unlock()
+					continue
+				}
 				if r.Timer > 0 {
 					r.Timer--
 				}
 				if r.Timer <= 0 {
 					r.Timer = 0
 					r.timerRunning = false
+					outcome := resolveActiveVote(r)
 					r.mu.Unlock()
-					handleTallyVotes(r)
+
+					appendMatchRecord(outcome.record)
+					if outcome.kicked != nil {
+						notifyKicked(outcome.kicked)
+					}
 					broadcastRoom(r)
 					return
 				}
@@ -380,106 +425,19 @@ func handleGuessCorrect(room *Room) {
 			room.timerCancel = nil
 		}
 	}
-	// ทายถูก → ไป phase โหวต (คะแนนไปตัดสินที่ handleTallyVotes)
+	// ทายถูก → ไป phase โหวต (คะแนนไปตัดสินที่ resolveActiveVote)
 	room.RoundEndByTimeout = false
 	room.State = "voting"
-	room.Votes = make(map[string]string)
-	room.Voted = make(map[string]bool)
-	room.BlockedVoters = make(map[string]bool)
-	room.LastVotes = []VotePair{}
+	_ = startVote(room, VoteInsider, room.JudgeID, "", 0)
 	room.mu.Unlock()
 
 	broadcastRoom(room)
 	startVoteTimer(room, VoteDurationSeconds)
 }
 
-func handleTallyVotes(room *Room) {
-	room.mu.Lock()
-	defer room.mu.Unlock()
-
-	if len(room.Players) == 0 {
-		return
-	}
-
-	// เก็บประวัติว่าใครโหวตใคร
-	lastVotes := make([]VotePair, 0, len(room.Votes))
-	for voterID, targetID := range room.Votes {
-		lastVotes = append(lastVotes, VotePair{
-			VoterID:  voterID,
-			TargetID: targetID,
-		})
-	}
-	room.LastVotes = lastVotes
-
-	// นับคะแนน
-	count := make(map[string]int)
-	for _, suspectID := range room.Votes {
-		count[suspectID]++
-	}
-
-	if len(count) == 0 {
-		// ไม่มีใครโหวต → จบรอบ แบบไม่มีใครได้แต้มเพิ่ม
-		room.State = "scoreboard"
-		room.Votes = make(map[string]string)
-		room.Voted = make(map[string]bool)
-		room.BlockedVoters = make(map[string]bool)
-		return
-	}
-
-	// หา max vote
-	maxVote := -1
-	for _, c := range count {
-		if c > maxVote {
-			maxVote = c
-		}
-	}
-
-	// คนที่ได้คะแนนสูงสุด
-	top := []string{}
-	for id, c := range count {
-		if c == maxVote {
-			top = append(top, id)
-		}
-	}
-
-	// เสมอ → โหวตรอบใหม่ โดย "ผู้ต้องสงสัยที่คะแนนเท่ากัน" ถูก block ไม่ให้โหวต
-	if len(top) > 1 {
-		room.State = "voting"
-		room.Votes = make(map[string]string)
-		room.Voted = make(map[string]bool)
-
-		room.BlockedVoters = make(map[string]bool)
-		for _, id := range top {
-			room.BlockedVoters[id] = true
-		}
-		return
-	}
-
-	// มีผู้ถูกโหวตชัดเจน
-	votedID := top[0]
-	isCorrect := votedID == room.InsiderID
-
-	if isCorrect {
-		// โหวตโดน Insider → คนทั่วไปชนะ (ไม่รวม Insider / Judge)
-		for _, p := range room.Players {
-			if p.ID == room.InsiderID || p.ID == room.JudgeID {
-				continue
-			}
-			p.Score++
-		}
-	} else {
-		// โหวตผิด → Insider ชนะคนเดียว
-		if ins, ok := room.Players[room.InsiderID]; ok {
-			ins.Score += 2 // จะปรับเป็น 1 แต้มก็ได้
-		}
-	}
-
-	room.State = "scoreboard"
-	room.Votes = make(map[string]string)
-	room.Voted = make(map[string]bool)
-	room.BlockedVoters = make(map[string]bool)
-}
-
+// handleNextRound resets the room back to lobby for a new round. Its own state change is
+// persisted via persistRoomLocked (broadcastRoom is always called right after), so it
+// doesn't need to append a MatchRecord itself - the tally already did that.
 func handleNextRound(room *Room) {
 	room.mu.Lock()
 	defer room.mu.Unlock()
@@ -495,329 +453,198 @@ func handleNextRound(room *Room) {
 		room.timerCancel = nil
 	}
 	room.State = "lobby"
-	room.Votes = make(map[string]string)
 	room.RoundEndByTimeout = false
-	room.BlockedVoters = make(map[string]bool)
-	room.Voted = make(map[string]bool)
-	room.LastVotes = []VotePair{}
+	room.ActiveVote = nil
+	room.ExtendTimerUsed = false
+	if room.voteCancel != nil {
+		close(room.voteCancel)
+		room.voteCancel = nil
+	}
 }
 
 func wsHandler(c *websocket.Conn) {
 	roomCode := c.Query("room")
 	playerName := c.Query("name")
 	mode := c.Query("mode")
+	token := c.Query("token")
 
 	if roomCode == "" || playerName == "" {
-		sendError(c, "missing room or name")
+		rpcConnError(c, RPCErrInvalidParams, "missing room or name")
 		_ = c.Close()
 		return
 	}
 
 	create := mode == "create"
-	room, ok := getOrCreateRoom(roomCode, create)
-	if !ok || room == nil {
-		if create {
-			sendError(c, "ห้องนี้มีอยู่แล้ว กรุณาใช้รหัสห้องอื่น หรือกดเข้าห้องแทน")
-		} else {
-			sendError(c, "room not found")
+	if create && !allowRoomCreate(clientIP(c)) {
+		rpcConnError(c, RPCErrRateLimited, "สร้างห้องบ่อยเกินไป กรุณาลองใหม่ภายหลัง")
+		_ = c.Close()
+		return
+	}
+
+	room, err := getOrCreateRoom(roomCode, create)
+	if err != nil {
+		switch {
+		case errors.As(err, new(ErrTooManyRooms)):
+			triggerPrune()
+			rpcConnError(c, RPCErrBadState, "เซิร์ฟเวอร์มีห้องเต็มแล้ว กรุณาลองใหม่ภายหลัง")
+		case errors.Is(err, ErrRoomExists):
+			rpcConnError(c, RPCErrBadState, "ห้องนี้มีอยู่แล้ว กรุณาใช้รหัสห้องอื่น หรือกดเข้าห้องแทน")
+		default:
+			rpcConnError(c, RPCErrBadState, "room not found")
 		}
 		_ = c.Close()
 		return
 	}
 
-	playerID := makePlayerID()
-	player := &Player{
-		ID:    playerID,
-		Name:  playerName,
-		Score: 0,
-		Role:  "",
-		Conn:  c,
+	var playerID string
+	var player *Player
+	reconnected := false
+
+	// token= มาจาก session message ที่เราออกให้ตอน connect ครั้งก่อน ถ้ายังไม่หมดอายุ
+	// และ Player ยังอยู่ในห้อง (รวมถึงช่วง grace ที่ถูก mark disconnected) ก็ rebind แทนสร้างใหม่
+	if token != "" {
+		if tokRoom, tokPlayerID, ok := parseSessionToken(token); ok && tokRoom == roomCode {
+			room.mu.Lock()
+			if existing, found := room.Players[tokPlayerID]; found {
+				if existing.graceCancel != nil {
+					close(existing.graceCancel)
+					existing.graceCancel = nil
+				}
+				if existing.Conn != nil && existing.Conn != c {
+					_ = existing.Conn.Close()
+				}
+				existing.Conn = c
+				existing.Disconnected = false
+				player = existing
+				playerID = existing.ID
+				reconnected = true
+			}
+			room.mu.Unlock()
+		}
+	}
+
+	if player == nil {
+		playerID = makePlayerID()
+		player = &Player{
+			ID:    playerID,
+			Name:  playerName,
+			Score: 0,
+			Role:  "",
+			Conn:  c,
+		}
+
+		room.mu.Lock()
+		room.Players[playerID] = player
+		if room.HostID == "" {
+			room.HostID = playerID
+		}
+		room.mu.Unlock()
 	}
 
+	expiry := time.Now().Add(sessionTTL)
 	room.mu.Lock()
-	room.Players[playerID] = player
-	if room.HostID == "" {
-		room.HostID = playerID
-	}
+	currentSeq := room.seq
 	room.mu.Unlock()
+	notify(c, "room.session", SessionMessage{
+		Token:     signSessionToken(roomCode, playerID, expiry),
+		PlayerID:  playerID,
+		LastSeq:   currentSeq,
+		ExpiresAt: expiry.Unix(),
+	})
+
+	if reconnected {
+		lastSeenSeq, _ := strconv.ParseUint(c.Query("lastSeq"), 10, 64)
+		room.mu.Lock()
+		replayHistory(room, player, lastSeenSeq)
+		room.mu.Unlock()
+		log.Printf("[WS] %s reconnected to room %s\n", playerName, roomCode)
+	} else {
+		log.Printf("[WS] %s joined room %s (mode=%s)\n", playerName, roomCode, mode)
+	}
 
 	sendRoomToPlayer(room, player)
 	broadcastRoom(room)
 
-	log.Printf("[WS] %s joined room %s (mode=%s)\n", playerName, roomCode, mode)
-
 	defer func() {
-		log.Printf("[WS] %s disconnected from room %s\n", playerName, roomCode)
-
 		room.mu.Lock()
-		delete(room.Players, playerID)
-		if room.HostID == playerID {
-			room.HostID = ""
-			for id := range room.Players {
-				room.HostID = id
-				break
-			}
-		}
-		if room.JudgeID == playerID {
-			room.JudgeID = ""
+		p, stillPresent := room.Players[playerID]
+		if !stillPresent || p.Conn != c {
+			// ไม่ใช่ connection ปัจจุบันของ player นี้แล้ว (โดน reconnect แทนที่ไปก่อนแล้ว)
+			// ห้าม mark disconnected ทับ socket ใหม่ที่เพิ่งต่อเข้ามา
+			room.mu.Unlock()
+			return
 		}
+		p.Conn = nil
+		p.Disconnected = true
+		graceCancel := make(chan struct{})
+		p.graceCancel = graceCancel
 		room.mu.Unlock()
 
+		log.Printf("[WS] %s disconnected from room %s, grace period started\n", playerName, roomCode)
 		broadcastRoom(room)
-		deleteRoomIfEmpty(room)
-	}()
-
-	for {
-		_, data, err := c.ReadMessage()
-		if err != nil {
-			return
-		}
-		var msg ClientMessage
-		if err := json.Unmarshal(data, &msg); err != nil {
-			sendError(c, "invalid message format")
-			continue
-		}
-
-		switch msg.Type {
-		case "set_judge":
-			room.mu.Lock()
-			if _, ok := room.Players[msg.TargetID]; ok {
-				room.JudgeID = msg.TargetID
-			}
-			room.mu.Unlock()
-			broadcastRoom(room)
-
-		case "set_chat_enabled":
-			if msg.ChatEnabled == nil {
-				sendError(c, "chatEnabled is required")
-				continue
-			}
-			room.mu.Lock()
-			if room.HostID != playerID {
-				room.mu.Unlock()
-				sendError(c, "เฉพาะ Host เท่านั้นที่ตั้งค่าแชทได้")
-				continue
-			}
-			room.ChatEnabled = *msg.ChatEnabled
-			room.mu.Unlock()
-			broadcastRoom(room)
-
-		case "start_round":
-			if msg.Duration <= 0 {
-				msg.Duration = RoundDurationSeconds
-			}
-
-			room.mu.Lock()
-			totalPlayers := len(room.Players)
-			hasJudge := room.JudgeID != ""
-			nonJudgeCount := totalPlayers
-			if hasJudge {
-				nonJudgeCount = totalPlayers - 1
-			}
-			room.SecretWord = msg.SecretWord
-			room.mu.Unlock()
-
-			if msg.SecretWord == "" {
-				sendError(c, "กรรมการต้องกำหนดคำปริศนาก่อนเริ่มเกม")
-				continue
-			}
-			if !hasJudge || nonJudgeCount < 3 {
-				sendError(c, "ต้องมีผู้เล่น (ไม่นับกรรมการ) อย่างน้อย 3 คน")
-				continue
-			}
-
-			assignRoles(room)
-			broadcastRoom(room)
-			startCountdownTimer(room, msg.Duration)
-
-		case "guess_correct":
-			room.mu.Lock()
-			isJudge := room.JudgeID == playerID
-			room.mu.Unlock()
-			if !isJudge {
-				sendError(c, "เฉพาะกรรมการเท่านั้นที่กดทายถูกได้")
-				continue
-			}
-			handleGuessCorrect(room)
-
-		case "vote_insider":
-			if msg.SuspectID == "" {
-				sendError(c, "suspectId is required")
-				continue
-			}
-
-			room.mu.Lock()
-
-			if room.State != "voting" {
-				room.mu.Unlock()
-				sendError(c, "ยังไม่อยู่ในช่วงโหวต")
-				continue
-			}
-
-			if playerID == room.JudgeID {
-				room.mu.Unlock()
-				sendError(c, "กรรมการไม่สามารถโหวตได้")
-				continue
-			}
-
-			if room.BlockedVoters != nil && room.BlockedVoters[playerID] {
-				room.mu.Unlock()
-				sendError(c, "คุณอยู่ในกลุ่มที่ถูกสงสัย จึงไม่มีสิทธิ์โหวตรอบนี้")
-				continue
-			}
-
-			if msg.SuspectID == playerID {
-				room.mu.Unlock()
-				sendError(c, "ไม่สามารถโหวตตัวเองได้")
-				continue
-			}
-
-			if _, ok := room.Players[msg.SuspectID]; !ok {
-				room.mu.Unlock()
-				sendError(c, "invalid suspectId")
-				continue
-			}
-
-			if room.Votes == nil {
-				room.Votes = make(map[string]string)
-			}
-			room.Votes[playerID] = msg.SuspectID
-
-			// mark คนนี้ว่าโหวตแล้ว (ให้ front-end ใช้โชว์)
-			if room.Voted == nil {
-				room.Voted = make(map[string]bool)
-			}
-			room.Voted[playerID] = true
-
-			// คำนวณจำนวน "คนที่มีสิทธิ์โหวตจริง ๆ"
-			eligible := 0
-			for id := range room.Players {
-				if id == room.JudgeID {
-					continue
-				}
-				if room.BlockedVoters != nil && room.BlockedVoters[id] {
-					continue
-				}
-				eligible++
-			}
 
-			if len(room.Votes) >= eligible && eligible > 0 {
-				if room.timerRunning {
-					room.timerRunning = false
-					if room.timerCancel != nil {
-						close(room.timerCancel)
-						room.timerCancel = nil
+		go func() {
+			select {
+			case <-time.After(reconnectGraceSeconds * time.Second):
+				room.mu.Lock()
+				if p, stillPresent := room.Players[playerID]; stillPresent && p.Disconnected {
+					delete(room.Players, playerID)
+					if room.HostID == playerID {
+						room.HostID = ""
+						for id := range room.Players {
+							room.HostID = id
+							break
+						}
+					}
+					if room.JudgeID == playerID {
+						room.JudgeID = ""
 					}
 				}
 				room.mu.Unlock()
-				handleTallyVotes(room)
 				broadcastRoom(room)
-			} else {
-				room.mu.Unlock()
-				broadcastRoom(room)
-			}
-
-		case "next_round":
-			handleNextRound(room)
-			broadcastRoom(room)
-
-		case "kick":
-			room.mu.Lock()
-
-			if room.HostID != playerID {
-				room.mu.Unlock()
-				sendError(c, "เฉพาะ Host เท่านั้นที่เตะผู้เล่นได้")
-				continue
-			}
-
-			if msg.TargetID == "" {
-				room.mu.Unlock()
-				sendError(c, "targetId is required")
-				continue
-			}
-
-			if msg.TargetID == room.HostID {
-				room.mu.Unlock()
-				sendError(c, "ไม่สามารถเตะตัวเองได้")
-				continue
-			}
-
-			target, ok := room.Players[msg.TargetID]
-			if !ok {
-				room.mu.Unlock()
-				sendError(c, "ผู้เล่นที่ต้องการเตะไม่อยู่ในห้องแล้ว")
-				continue
-			}
-
-			if room.JudgeID == msg.TargetID {
-				room.JudgeID = ""
-			}
-
-			delete(room.Players, msg.TargetID)
-			room.mu.Unlock()
-
-			if target.Conn != nil {
-				_ = target.Conn.WriteJSON(ErrorMessage{
-					Type:    "error",
-					Message: "คุณถูกเชิญออกจากห้องโดย Host",
-				})
-				_ = target.Conn.Close()
-			}
-
-			broadcastRoom(room)
-			deleteRoomIfEmpty(room)
-
-		case "chat":
-			txt := strings.TrimSpace(msg.Text)
-			if txt == "" {
-				continue
-			}
-			if len(txt) > 300 {
-				txt = txt[:300]
-			}
-
-			room.mu.Lock()
-			enabled := room.ChatEnabled
-			sender, ok := room.Players[playerID]
-			room.mu.Unlock()
-
-			if !ok || sender == nil {
-				continue
+				deleteRoomIfEmpty(room)
+			case <-graceCancel:
 			}
+		}()
+	}()
 
-			if !enabled {
-				sendError(c, "ตอนนี้ Host ปิดแชทอยู่")
-				continue
-			}
+	rpcCtx := &rpcContext{Room: room, PlayerID: playerID, Conn: c}
 
-			payload := ChatPayload{
-				Type: "chat",
-				From: ChatFrom{
-					ID:   sender.ID,
-					Name: sender.Name,
-				},
-				Text: txt,
-				Ts:   time.Now().Unix(),
-			}
+	for {
+		_, data, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
 
-			room.mu.Lock()
-			for _, p := range room.Players {
-				if p.Conn == nil {
-					continue
-				}
-				_ = p.Conn.WriteJSON(payload)
-			}
-			room.mu.Unlock()
+		room.mu.Lock()
+		room.LastActivity = time.Now()
+		room.mu.Unlock()
 
-		default:
-			sendError(c, "unknown message type: "+msg.Type)
-		}
+		dispatchRPC(rpcCtx, data)
 	}
 }
 
 func main() {
+	storeKind := flag.String("store", "sqlite", "persistence backend: sqlite, memory, or postgres")
+	storeDSN := flag.String("store-dsn", "", "data source for the chosen -store backend (sqlite file path / postgres URL)")
+	flag.Parse()
+
+	backend, err := initStore(*storeKind, *storeDSN)
+	if err != nil {
+		log.Fatal("failed to init store:", err)
+	}
+	store = backend
+	defer store.Close()
+
+	rehydrateRooms()
+	startJanitor(janitorInterval, roomIdleTTL)
+
 	app := fiber.New()
 	app.Get("/ws", websocket.New(wsHandler))
+	app.Post("/admin/words/reload", reloadWordDecksHandler)
+	app.Post("/rooms/:code/decks", uploadRoomDeckHandler)
+	app.Get("/rooms/:code/history", roomHistoryHandler)
+	app.Get("/players/:name/recent", playerRecentMatchesHandler)
 
 	port := os.Getenv("PORT")
 	if port == "" {
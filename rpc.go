@@ -0,0 +1,462 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+// RPCRequest is one client -> server call: {"jsonrpc":"2.0","id":1,"method":"room.setJudge","params":{...}}
+// Every request carries an id, and the matching RPCResponse echoes it back - unlike the old
+// sendError strings, a client can now tell exactly which call an error belongs to.
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// RPCError is a typed, machine-checkable failure - {code, message} per JSON-RPC 2.0 section 5.1.
+// Codes below -32000 are the reserved JSON-RPC ones; app-specific codes start at 1000.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string { return e.Message }
+
+// RPCResponse replies to exactly one RPCRequest, echoing its id back.
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCNotification is a server-initiated push with no id - room snapshots, chat, kicks.
+type RPCNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+const (
+	RPCErrParse          = -32700
+	RPCErrMethodNotFound = -32601
+	RPCErrInvalidParams  = -32602
+
+	// App-specific codes (>=1000) so clients can branch on them without string-matching
+	// Thai error text.
+	RPCErrNotJudge    = 1001
+	RPCErrNotHost     = 1002
+	RPCErrBadState    = 1003
+	RPCErrRateLimited = 1004
+	RPCErrKicked      = 1005
+)
+
+var (
+	ErrNotJudge    = &RPCError{Code: RPCErrNotJudge, Message: "เฉพาะกรรมการเท่านั้นที่ทำรายการนี้ได้"}
+	ErrNotHost     = &RPCError{Code: RPCErrNotHost, Message: "เฉพาะ Host เท่านั้นที่ทำรายการนี้ได้"}
+	ErrBadState    = &RPCError{Code: RPCErrBadState, Message: "สถานะห้องไม่ถูกต้องสำหรับการกระทำนี้"}
+	ErrRateLimited = &RPCError{Code: RPCErrRateLimited, Message: "ทำรายการบ่อยเกินไป กรุณาลองใหม่ภายหลัง"}
+)
+
+func newRPCError(code int, message string) *RPCError {
+	return &RPCError{Code: code, Message: message}
+}
+
+func rpcInvalidParams(message string) *RPCError {
+	return newRPCError(RPCErrInvalidParams, message)
+}
+
+// rpcOK is the default {"ok":true} result for handlers that don't have anything else to return.
+var rpcOK = map[string]bool{"ok": true}
+
+// rpcContext carries per-request state into a handler: the room, the calling player's id,
+// and their live connection (for targeted sends outside the broadcast, e.g. room.session).
+type rpcContext struct {
+	Room     *Room
+	PlayerID string
+	Conn     *websocket.Conn
+}
+
+// rpcHandler validates params into its own method-specific struct and performs the call.
+// A non-nil result is marshaled into RPCResponse.Result; a non-nil *RPCError short-circuits
+// to RPCResponse.Error instead.
+type rpcHandler func(ctx *rpcContext, params json.RawMessage) (interface{}, *RPCError)
+
+// rpcMethods is the typed method registry - no reflection, just a map literal, same as
+// every other lookup table in this codebase (see wordDecks, voteDurationSeconds).
+var rpcMethods = map[string]rpcHandler{
+	"room.setJudge":       handleRPCSetJudge,
+	"room.setChatEnabled": handleRPCSetChatEnabled,
+	"room.setCategory":    handleRPCSetCategory,
+	"room.kick":           handleRPCKick,
+	"round.start":         handleRPCStartRound,
+	"round.guessCorrect":  handleRPCGuessCorrect,
+	"round.next":          handleRPCNextRound,
+	"vote.start":          handleRPCStartVote,
+	"vote.cast":           handleRPCCastVote,
+	"chat.send":           handleRPCChatSend,
+}
+
+func parseRPCParams(raw json.RawMessage, v interface{}) *RPCError {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return rpcInvalidParams("invalid params: " + err.Error())
+	}
+	return nil
+}
+
+// dispatchRPC parses one client frame as an RPCRequest, routes it through rpcMethods, and
+// writes back the matching RPCResponse (always carrying the request's id, even on error).
+func dispatchRPC(ctx *rpcContext, data []byte) {
+	var req RPCRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		writeRPCResponse(ctx.Conn, nil, nil, newRPCError(RPCErrParse, "invalid message format"))
+		return
+	}
+
+	handler, ok := rpcMethods[req.Method]
+	if !ok {
+		writeRPCResponse(ctx.Conn, req.ID, nil, newRPCError(RPCErrMethodNotFound, "unknown method: "+req.Method))
+		return
+	}
+
+	result, rpcErr := handler(ctx, req.Params)
+	writeRPCResponse(ctx.Conn, req.ID, result, rpcErr)
+}
+
+func writeRPCResponse(conn *websocket.Conn, id json.RawMessage, result interface{}, rpcErr *RPCError) {
+	resp := RPCResponse{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		if result == nil {
+			result = rpcOK
+		}
+		resp.Result = result
+	}
+	_ = conn.WriteJSON(resp)
+}
+
+// notify pushes a server-initiated RPCNotification (no id) to a single connection.
+func notify(conn *websocket.Conn, method string, params interface{}) {
+	if conn == nil {
+		return
+	}
+	_ = conn.WriteJSON(RPCNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// rpcConnError reports a failure from before any RPCRequest exists yet (the ws handshake
+// itself) using the same {code, message} shape as every other RPC error, with a null id.
+func rpcConnError(conn *websocket.Conn, code int, message string) {
+	writeRPCResponse(conn, nil, nil, newRPCError(code, message))
+}
+
+type setJudgeParams struct {
+	TargetID string `json:"targetId"`
+}
+
+func handleRPCSetJudge(ctx *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+	var p setJudgeParams
+	if rpcErr := parseRPCParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	room := ctx.Room
+	room.mu.Lock()
+	if _, ok := room.Players[p.TargetID]; ok {
+		room.JudgeID = p.TargetID
+	}
+	room.mu.Unlock()
+
+	broadcastRoom(room)
+	return nil, nil
+}
+
+type setChatEnabledParams struct {
+	ChatEnabled *bool `json:"chatEnabled"`
+}
+
+func handleRPCSetChatEnabled(ctx *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+	var p setChatEnabledParams
+	if rpcErr := parseRPCParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if p.ChatEnabled == nil {
+		return nil, rpcInvalidParams("chatEnabled is required")
+	}
+
+	room := ctx.Room
+	room.mu.Lock()
+	if room.HostID != ctx.PlayerID {
+		room.mu.Unlock()
+		return nil, ErrNotHost
+	}
+	room.ChatEnabled = *p.ChatEnabled
+	room.mu.Unlock()
+
+	broadcastRoom(room)
+	return nil, nil
+}
+
+type startRoundParams struct {
+	Duration   int    `json:"duration,omitempty"`
+	SecretWord string `json:"secretWord,omitempty"`
+}
+
+func handleRPCStartRound(ctx *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+	var p startRoundParams
+	if rpcErr := parseRPCParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if p.Duration <= 0 {
+		p.Duration = RoundDurationSeconds
+	}
+
+	room := ctx.Room
+	room.mu.Lock()
+	totalPlayers := len(room.Players)
+	hasJudge := room.JudgeID != ""
+	nonJudgeCount := totalPlayers
+	if hasJudge {
+		nonJudgeCount = totalPlayers - 1
+	}
+
+	if !hasJudge || nonJudgeCount < 3 {
+		room.mu.Unlock()
+		return nil, newRPCError(RPCErrBadState, "ต้องมีผู้เล่น (ไม่นับกรรมการ) อย่างน้อย 3 คน")
+	}
+
+	secretWord := p.SecretWord
+	var pickErr error
+	if secretWord == "" {
+		secretWord, pickErr = pickWordForRoom(room)
+	}
+	if pickErr == nil {
+		room.SecretWord = secretWord
+	}
+	room.mu.Unlock()
+
+	if pickErr != nil {
+		return nil, rpcInvalidParams(pickErr.Error())
+	}
+
+	assignRoles(room)
+	broadcastRoom(room)
+	sendSecretWord(room)
+	startCountdownTimer(room, p.Duration)
+
+	return nil, nil
+}
+
+type setCategoryParams struct {
+	Category string `json:"category"`
+}
+
+func handleRPCSetCategory(ctx *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+	var p setCategoryParams
+	if rpcErr := parseRPCParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if p.Category == "" {
+		return nil, rpcInvalidParams("category is required")
+	}
+
+	room := ctx.Room
+	room.mu.Lock()
+	if room.HostID != ctx.PlayerID && room.JudgeID != ctx.PlayerID {
+		room.mu.Unlock()
+		return nil, newRPCError(RPCErrNotHost, "เฉพาะ Host หรือกรรมการเท่านั้นที่เลือกหมวดคำได้")
+	}
+	if _, ok := getWordDeck(p.Category); !ok {
+		if _, ok := room.CustomDecks[p.Category]; !ok {
+			room.mu.Unlock()
+			return nil, rpcInvalidParams("ไม่พบหมวดคำนี้")
+		}
+	}
+	room.Category = p.Category
+	room.mu.Unlock()
+
+	broadcastRoom(room)
+	return nil, nil
+}
+
+func handleRPCGuessCorrect(ctx *rpcContext, _ json.RawMessage) (interface{}, *RPCError) {
+	room := ctx.Room
+	room.mu.Lock()
+	isJudge := room.JudgeID == ctx.PlayerID
+	room.mu.Unlock()
+	if !isJudge {
+		return nil, ErrNotJudge
+	}
+
+	handleGuessCorrect(room)
+	return nil, nil
+}
+
+type startVoteParams struct {
+	VoteType string `json:"voteType"`
+	TargetID string `json:"targetId,omitempty"`
+	Seconds  int    `json:"seconds,omitempty"`
+}
+
+func handleRPCStartVote(ctx *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+	var p startVoteParams
+	if rpcErr := parseRPCParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+	if p.VoteType == "" {
+		return nil, rpcInvalidParams("voteType is required")
+	}
+
+	room := ctx.Room
+	room.mu.Lock()
+	err := startVote(room, VoteType(p.VoteType), ctx.PlayerID, p.TargetID, p.Seconds)
+	room.mu.Unlock()
+	if err != nil {
+		return nil, rpcInvalidParams(err.Error())
+	}
+
+	broadcastRoom(room)
+	return nil, nil
+}
+
+type castVoteParams struct {
+	Choice string `json:"choice"`
+}
+
+func handleRPCCastVote(ctx *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+	var p castVoteParams
+	if rpcErr := parseRPCParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	room := ctx.Room
+	room.mu.Lock()
+	if room.ActiveVote == nil {
+		room.mu.Unlock()
+		return nil, ErrBadState
+	}
+	outcome, err := castVote(room, ctx.PlayerID, p.Choice)
+	room.mu.Unlock()
+	if err != nil {
+		return nil, rpcInvalidParams(err.Error())
+	}
+
+	appendMatchRecord(outcome.record)
+	if outcome.kicked != nil {
+		notifyKicked(outcome.kicked)
+	}
+	broadcastRoom(room)
+	return nil, nil
+}
+
+func handleRPCNextRound(ctx *rpcContext, _ json.RawMessage) (interface{}, *RPCError) {
+	handleNextRound(ctx.Room)
+	broadcastRoom(ctx.Room)
+	return nil, nil
+}
+
+type kickParams struct {
+	TargetID string `json:"targetId"`
+}
+
+func handleRPCKick(ctx *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+	var p kickParams
+	if rpcErr := parseRPCParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	room := ctx.Room
+	room.mu.Lock()
+
+	if room.HostID != ctx.PlayerID {
+		room.mu.Unlock()
+		return nil, ErrNotHost
+	}
+	if p.TargetID == "" {
+		room.mu.Unlock()
+		return nil, rpcInvalidParams("targetId is required")
+	}
+	if p.TargetID == room.HostID {
+		room.mu.Unlock()
+		return nil, rpcInvalidParams("ไม่สามารถเตะตัวเองได้")
+	}
+
+	target, ok := room.Players[p.TargetID]
+	if !ok {
+		room.mu.Unlock()
+		return nil, rpcInvalidParams("ผู้เล่นที่ต้องการเตะไม่อยู่ในห้องแล้ว")
+	}
+
+	if room.JudgeID == p.TargetID {
+		room.JudgeID = ""
+	}
+	delete(room.Players, p.TargetID)
+	room.mu.Unlock()
+
+	if target.Conn != nil {
+		notify(target.Conn, "room.kicked", newRPCError(RPCErrKicked, "คุณถูกเชิญออกจากห้องโดย Host"))
+		_ = target.Conn.Close()
+	}
+
+	broadcastRoom(room)
+	deleteRoomIfEmpty(room)
+
+	return nil, nil
+}
+
+type chatSendParams struct {
+	Text string `json:"text"`
+}
+
+func handleRPCChatSend(ctx *rpcContext, params json.RawMessage) (interface{}, *RPCError) {
+	var p chatSendParams
+	if rpcErr := parseRPCParams(params, &p); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	txt := strings.TrimSpace(p.Text)
+	if txt == "" {
+		return nil, nil
+	}
+	if len(txt) > 300 {
+		txt = txt[:300]
+	}
+
+	room := ctx.Room
+	room.mu.Lock()
+	enabled := room.ChatEnabled
+	sender, ok := room.Players[ctx.PlayerID]
+	room.mu.Unlock()
+
+	if !ok || sender == nil {
+		return nil, nil
+	}
+	if !enabled {
+		return nil, newRPCError(RPCErrBadState, "ตอนนี้ Host ปิดแชทอยู่")
+	}
+
+	payload := ChatPayload{
+		From: ChatFrom{ID: sender.ID, Name: sender.Name},
+		Text: txt,
+		Ts:   time.Now().Unix(),
+	}
+	notification := RPCNotification{JSONRPC: "2.0", Method: "chat.message", Params: payload}
+
+	room.mu.Lock()
+	pushHistory(room, notification)
+	for _, pl := range room.Players {
+		if pl.Conn == nil {
+			continue
+		}
+		_ = pl.Conn.WriteJSON(notification)
+	}
+	room.mu.Unlock()
+
+	return nil, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionTTL คืออายุของ token ที่ใช้ reconnect เข้าห้องเดิม
+const sessionTTL = 4 * time.Hour
+
+var sessionSecret []byte
+
+func init() {
+	if secret := os.Getenv("SESSION_SECRET"); secret != "" {
+		sessionSecret = []byte(secret)
+		return
+	}
+
+	log.Println("[session] SESSION_SECRET not set, generating ephemeral secret (tokens won't survive a restart)")
+	sessionSecret = make([]byte, 32)
+	if _, err := rand.Read(sessionSecret); err != nil {
+		log.Fatal("failed to generate session secret:", err)
+	}
+}
+
+// signSessionToken ออก token แบบ roomCode|playerID|expiry ที่เซ็นด้วย HMAC-SHA256
+// เพื่อให้ client เก็บไว้แล้วใช้ reconnect กลับเข้าห้องเดิมได้โดยไม่ต้องเชื่อถือ client
+func signSessionToken(roomCode, playerID string, expiry time.Time) string {
+	payload := roomCode + "|" + playerID + "|" + strconv.FormatInt(expiry.Unix(), 10)
+	mac := hmac.New(sha256.New, sessionSecret)
+	mac.Write([]byte(payload))
+	sig := hex.EncodeToString(mac.Sum(nil))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + sig
+}
+
+// parseSessionToken ตรวจลายเซ็นและความหมดอายุ แล้วคืน roomCode/playerID ถ้าใช้ได้
+func parseSessionToken(token string) (roomCode string, playerID string, ok bool) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	payloadRaw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", "", false
+	}
+	payload := string(payloadRaw)
+
+	mac := hmac.New(sha256.New, sessionSecret)
+	mac.Write(payloadRaw)
+	expectedSig := hex.EncodeToString(mac.Sum(nil))
+	if subtle.ConstantTimeCompare([]byte(expectedSig), []byte(parts[1])) != 1 {
+		return "", "", false
+	}
+
+	fields := strings.SplitN(payload, "|", 3)
+	if len(fields) != 3 {
+		return "", "", false
+	}
+
+	expiryUnix, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return "", "", false
+	}
+	if time.Now().Unix() > expiryUnix {
+		return "", "", false
+	}
+
+	return fields[0], fields[1], true
+}
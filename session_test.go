@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignAndParseSessionTokenRoundTrip(t *testing.T) {
+	expiry := time.Now().Add(sessionTTL)
+	token := signSessionToken("ABCD", "player-1", expiry)
+
+	roomCode, playerID, ok := parseSessionToken(token)
+	if !ok {
+		t.Fatal("parseSessionToken rejected a freshly signed token")
+	}
+	if roomCode != "ABCD" || playerID != "player-1" {
+		t.Fatalf("got roomCode=%q playerID=%q, want ABCD/player-1", roomCode, playerID)
+	}
+}
+
+func TestParseSessionTokenRejectsExpired(t *testing.T) {
+	token := signSessionToken("ABCD", "player-1", time.Now().Add(-time.Minute))
+
+	if _, _, ok := parseSessionToken(token); ok {
+		t.Fatal("parseSessionToken accepted an expired token")
+	}
+}
+
+func TestParseSessionTokenRejectsTamperedPayload(t *testing.T) {
+	token := signSessionToken("ABCD", "player-1", time.Now().Add(sessionTTL))
+	tampered := token[:len(token)-1] + "x"
+
+	if _, _, ok := parseSessionToken(tampered); ok {
+		t.Fatal("parseSessionToken accepted a tampered signature")
+	}
+}
+
+func TestParseSessionTokenRejectsMalformedToken(t *testing.T) {
+	cases := []string{"", "no-dot-here", "onlyonepart.", ".sig"}
+	for _, c := range cases {
+		if _, _, ok := parseSessionToken(c); ok {
+			t.Fatalf("parseSessionToken accepted malformed token %q", c)
+		}
+	}
+}
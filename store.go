@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrNotImplemented is returned by Store backends that are stubbed out for future work
+// (e.g. Postgres) so callers can fail loudly instead of silently losing data.
+var ErrNotImplemented = errors.New("store: not implemented")
+
+// PlayerState is the persisted, plain-data shape of a Player (no *websocket.Conn, no
+// grace-period channel) used by RoomState.
+type PlayerState struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+	Role  string `json:"role"`
+}
+
+// RoomState is the persisted, plain-data shape of a Room. It's written on every broadcast
+// and read back on boot so an in-flight lobby survives a redeploy.
+type RoomState struct {
+	Code              string        `json:"code"`
+	State             string        `json:"state"`
+	HostID            string        `json:"hostId"`
+	JudgeID           string        `json:"judgeId"`
+	InsiderID         string        `json:"insiderId"`
+	SecretWord        string        `json:"secretWord"`
+	Category          string        `json:"category"`
+	ChatEnabled       bool          `json:"chatEnabled"`
+	RoundEndByTimeout bool          `json:"roundEndByTimeout"`
+	Players           []PlayerState `json:"players"`
+}
+
+// MatchRecord is one completed round, appended on every resolved insider vote so match
+// history survives restarts.
+type MatchRecord struct {
+	ID             int64             `json:"id"`
+	RoomCode       string            `json:"roomCode"`
+	Timestamp      int64             `json:"timestamp"`
+	InsiderID      string            `json:"insiderId"`
+	SecretWord     string            `json:"secretWord"`
+	Tally          map[string]int    `json:"tally"`       // suspectID -> vote count
+	ScoreDeltas    map[string]int    `json:"scoreDeltas"` // playerID -> score change this round
+	PlayerNames    map[string]string `json:"playerNames"` // playerID -> name, for ListRecentMatches
+	EndedByTimeout bool              `json:"endedByTimeout"`
+}
+
+// Store persists room state and match history behind a backend-agnostic interface, so the
+// server can run against SQLite in prod, an in-memory store in tests, or (later) Postgres.
+// Selected at boot via the -store flag (see initStore).
+type Store interface {
+	SaveRoom(state *RoomState) error
+	LoadRoom(code string) (*RoomState, bool, error)
+	LoadAllRooms() ([]*RoomState, error)
+	DeleteRoom(code string) error
+
+	AppendMatch(match *MatchRecord) error
+	ListRoomHistory(roomCode string, limit int) ([]*MatchRecord, error)
+	ListRecentMatches(playerName string, limit int) ([]*MatchRecord, error)
+
+	Close() error
+}
+
+// store is the process-wide Store backend, set up in main() from the -store flag.
+// It's nil in contexts that never called initStore (keeps persistence best-effort, see
+// persistRoomLocked/appendMatchRecord below).
+var store Store
+
+// toRoomState captures a plain-data snapshot of room. ต้องเรียกตอนถือ room.mu อยู่แล้ว
+func toRoomState(room *Room) *RoomState {
+	state := &RoomState{
+		Code:              room.Code,
+		State:             room.State,
+		HostID:            room.HostID,
+		JudgeID:           room.JudgeID,
+		InsiderID:         room.InsiderID,
+		SecretWord:        room.SecretWord,
+		Category:          room.Category,
+		ChatEnabled:       room.ChatEnabled,
+		RoundEndByTimeout: room.RoundEndByTimeout,
+		Players:           make([]PlayerState, 0, len(room.Players)),
+	}
+
+	for _, p := range room.Players {
+		state.Players = append(state.Players, PlayerState{
+			ID:    p.ID,
+			Name:  p.Name,
+			Score: p.Score,
+			Role:  p.Role,
+		})
+	}
+
+	return state
+}
+
+// roomFromState rebuilds a *Room from a persisted snapshot for rehydration on boot.
+// Transient things (connections, timers, history ring buffer) start empty - a rehydrated
+// room always comes back in "lobby" so a player can safely rejoin rather than resuming a
+// timer no one is watching.
+func roomFromState(state *RoomState) *Room {
+	room := &Room{
+		Code:         state.Code,
+		State:        "lobby",
+		HostID:       state.HostID,
+		JudgeID:      state.JudgeID,
+		Category:     state.Category,
+		ChatEnabled:  state.ChatEnabled,
+		Players:      make(map[string]*Player),
+		UsedWords:    make(map[string]map[string]bool),
+		CustomDecks:  make(map[string]*WordDeck),
+		LastActivity: time.Now(),
+	}
+
+	for _, p := range state.Players {
+		room.Players[p.ID] = &Player{
+			ID:    p.ID,
+			Name:  p.Name,
+			Score: p.Score,
+		}
+	}
+
+	return room
+}
+
+// persistRoomLocked saves room's current state to store. Best-effort: persistence must
+// never block or fail gameplay, so errors are only logged. ต้องเรียกตอนถือ room.mu อยู่แล้ว
+func persistRoomLocked(room *Room) {
+	if store == nil {
+		return
+	}
+	if err := store.SaveRoom(toRoomState(room)); err != nil {
+		log.Println("[store] failed to save room", room.Code, "-", err)
+	}
+}
+
+// buildMatchRecord captures the outcome of a vote tally for AppendMatch.
+// ต้องเรียกตอนถือ room.mu อยู่แล้ว และหลังคะแนนถูกบวกให้ผู้เล่นแล้ว
+func buildMatchRecord(room *Room, tally map[string]int, beforeScores map[string]int, playerNames map[string]string) *MatchRecord {
+	deltas := make(map[string]int, len(room.Players))
+	for id, p := range room.Players {
+		deltas[id] = p.Score - beforeScores[id]
+	}
+
+	return &MatchRecord{
+		RoomCode:       room.Code,
+		Timestamp:      time.Now().Unix(),
+		InsiderID:      room.InsiderID,
+		SecretWord:     room.SecretWord,
+		Tally:          tally,
+		ScoreDeltas:    deltas,
+		PlayerNames:    playerNames,
+		EndedByTimeout: room.RoundEndByTimeout,
+	}
+}
+
+// appendMatchRecord writes a completed round to the store. Best-effort, same rationale as
+// persistRoomLocked.
+func appendMatchRecord(record *MatchRecord) {
+	if store == nil || record == nil {
+		return
+	}
+	if err := store.AppendMatch(record); err != nil {
+		log.Println("[store] failed to append match for room", record.RoomCode, "-", err)
+	}
+}
+
+// rehydrateRooms loads every persisted room back into the in-memory `rooms` map on boot,
+// so an in-flight lobby isn't wiped out by a redeploy.
+func rehydrateRooms() {
+	if store == nil {
+		return
+	}
+
+	states, err := store.LoadAllRooms()
+	if err != nil {
+		log.Println("[store] failed to load rooms on boot -", err)
+		return
+	}
+
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	for _, state := range states {
+		if len(state.Players) == 0 {
+			continue
+		}
+		rooms[state.Code] = roomFromState(state)
+	}
+
+	log.Printf("[store] rehydrated %d room(s) from store\n", len(rooms))
+}
+
+// roomHistoryHandler is a read-only endpoint listing a room's recent completed matches.
+func roomHistoryHandler(c *fiber.Ctx) error {
+	if store == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "store not configured"})
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	matches, err := store.ListRoomHistory(c.Params("code"), limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"matches": matches})
+}
+
+// playerRecentMatchesHandler is a read-only endpoint listing a player's recent matches
+// across all rooms, keyed by display name.
+func playerRecentMatchesHandler(c *fiber.Ctx) error {
+	if store == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "store not configured"})
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	matches, err := store.ListRecentMatches(c.Params("name"), limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"matches": matches})
+}
+
+// initStore builds the Store backend named by kind (the -store flag): "sqlite" (default,
+// production), "memory" (tests / ephemeral runs), or "postgres" (reserved, not implemented
+// yet - see PostgresStore).
+func initStore(kind, dsn string) (Store, error) {
+	switch kind {
+	case "", "sqlite":
+		if dsn == "" {
+			dsn = "rooms.db"
+		}
+		return newSQLiteStore(dsn)
+	case "memory":
+		return newInMemoryStore(), nil
+	case "postgres":
+		return newPostgresStore(dsn)
+	default:
+		return nil, errors.New("store: unknown backend " + kind)
+	}
+}
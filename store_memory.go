@@ -0,0 +1,107 @@
+package main
+
+import "sync"
+
+// InMemoryStore is a Store backend that keeps everything in process memory. It's used for
+// tests and for `-store memory` runs where durability across restarts doesn't matter.
+type InMemoryStore struct {
+	mu      sync.Mutex
+	rooms   map[string]*RoomState
+	matches []*MatchRecord
+	nextID  int64
+}
+
+func newInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		rooms: make(map[string]*RoomState),
+	}
+}
+
+func (s *InMemoryStore) SaveRoom(state *RoomState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	players := append([]PlayerState(nil), state.Players...)
+	cp := *state
+	cp.Players = players
+	s.rooms[state.Code] = &cp
+	return nil
+}
+
+func (s *InMemoryStore) LoadRoom(code string) (*RoomState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.rooms[code]
+	if !ok {
+		return nil, false, nil
+	}
+	cp := *state
+	cp.Players = append([]PlayerState(nil), state.Players...)
+	return &cp, true, nil
+}
+
+func (s *InMemoryStore) LoadAllRooms() ([]*RoomState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make([]*RoomState, 0, len(s.rooms))
+	for _, state := range s.rooms {
+		cp := *state
+		cp.Players = append([]PlayerState(nil), state.Players...)
+		states = append(states, &cp)
+	}
+	return states, nil
+}
+
+func (s *InMemoryStore) DeleteRoom(code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.rooms, code)
+	return nil
+}
+
+func (s *InMemoryStore) AppendMatch(match *MatchRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	cp := *match
+	cp.ID = s.nextID
+	s.matches = append(s.matches, &cp)
+	return nil
+}
+
+func (s *InMemoryStore) ListRoomHistory(roomCode string, limit int) ([]*MatchRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*MatchRecord
+	for i := len(s.matches) - 1; i >= 0 && (limit <= 0 || len(out) < limit); i-- {
+		if s.matches[i].RoomCode == roomCode {
+			out = append(out, s.matches[i])
+		}
+	}
+	return out, nil
+}
+
+func (s *InMemoryStore) ListRecentMatches(playerName string, limit int) ([]*MatchRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*MatchRecord
+	for i := len(s.matches) - 1; i >= 0 && (limit <= 0 || len(out) < limit); i-- {
+		for _, name := range s.matches[i].PlayerNames {
+			if name == playerName {
+				out = append(out, s.matches[i])
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *InMemoryStore) Close() error {
+	return nil
+}
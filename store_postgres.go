@@ -0,0 +1,26 @@
+package main
+
+// PostgresStore is a placeholder Store backend reserved for a future Postgres-backed
+// deployment (e.g. multiple server instances sharing one database). Selecting it via
+// `-store postgres` fails fast with ErrNotImplemented instead of silently falling back to
+// SQLite.
+type PostgresStore struct{}
+
+func newPostgresStore(dsn string) (*PostgresStore, error) {
+	return nil, ErrNotImplemented
+}
+
+func (s *PostgresStore) SaveRoom(state *RoomState) error { return ErrNotImplemented }
+func (s *PostgresStore) LoadRoom(code string) (*RoomState, bool, error) {
+	return nil, false, ErrNotImplemented
+}
+func (s *PostgresStore) LoadAllRooms() ([]*RoomState, error)  { return nil, ErrNotImplemented }
+func (s *PostgresStore) DeleteRoom(code string) error         { return ErrNotImplemented }
+func (s *PostgresStore) AppendMatch(match *MatchRecord) error { return ErrNotImplemented }
+func (s *PostgresStore) ListRoomHistory(roomCode string, limit int) ([]*MatchRecord, error) {
+	return nil, ErrNotImplemented
+}
+func (s *PostgresStore) ListRecentMatches(playerName string, limit int) ([]*MatchRecord, error) {
+	return nil, ErrNotImplemented
+}
+func (s *PostgresStore) Close() error { return nil }
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore is the default production Store backend. Room snapshots are stored as a
+// single JSON blob keyed by room code (the shape changes too often for a rigid schema to
+// be worth it); match records get real columns since ListRecentMatches needs to filter by
+// player name efficiently.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func newSQLiteStore(dsn string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS rooms (
+			code       TEXT PRIMARY KEY,
+			data       TEXT NOT NULL,
+			updated_at INTEGER NOT NULL
+		)
+	`); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS matches (
+			id               INTEGER PRIMARY KEY AUTOINCREMENT,
+			room_code        TEXT NOT NULL,
+			ts               INTEGER NOT NULL,
+			insider_id       TEXT NOT NULL,
+			secret_word      TEXT NOT NULL,
+			ended_by_timeout INTEGER NOT NULL,
+			tally            TEXT NOT NULL,
+			score_deltas     TEXT NOT NULL,
+			player_names     TEXT NOT NULL
+		)
+	`); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_matches_room_code ON matches(room_code)`); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) SaveRoom(state *RoomState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO rooms (code, data, updated_at) VALUES (?, ?, unixepoch())
+		ON CONFLICT(code) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at
+	`, state.Code, string(data))
+	return err
+}
+
+func (s *SQLiteStore) LoadRoom(code string) (*RoomState, bool, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM rooms WHERE code = ?`, code).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var state RoomState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, false, err
+	}
+	return &state, true, nil
+}
+
+func (s *SQLiteStore) LoadAllRooms() ([]*RoomState, error) {
+	rows, err := s.db.Query(`SELECT data FROM rooms`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var states []*RoomState
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var state RoomState
+		if err := json.Unmarshal([]byte(data), &state); err != nil {
+			return nil, err
+		}
+		states = append(states, &state)
+	}
+	return states, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteRoom(code string) error {
+	_, err := s.db.Exec(`DELETE FROM rooms WHERE code = ?`, code)
+	return err
+}
+
+func (s *SQLiteStore) AppendMatch(match *MatchRecord) error {
+	tally, err := json.Marshal(match.Tally)
+	if err != nil {
+		return err
+	}
+	deltas, err := json.Marshal(match.ScoreDeltas)
+	if err != nil {
+		return err
+	}
+	names, err := json.Marshal(match.PlayerNames)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.Exec(`
+		INSERT INTO matches (room_code, ts, insider_id, secret_word, ended_by_timeout, tally, score_deltas, player_names)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, match.RoomCode, match.Timestamp, match.InsiderID, match.SecretWord, match.EndedByTimeout, string(tally), string(deltas), string(names))
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	match.ID = id
+	return nil
+}
+
+func (s *SQLiteStore) ListRoomHistory(roomCode string, limit int) ([]*MatchRecord, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.db.Query(`
+		SELECT id, room_code, ts, insider_id, secret_word, ended_by_timeout, tally, score_deltas, player_names
+		FROM matches WHERE room_code = ? ORDER BY ts DESC LIMIT ?
+	`, roomCode, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanMatchRows(rows)
+}
+
+func (s *SQLiteStore) ListRecentMatches(playerName string, limit int) ([]*MatchRecord, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.db.Query(`
+		SELECT id, room_code, ts, insider_id, secret_word, ended_by_timeout, tally, score_deltas, player_names
+		FROM matches WHERE player_names LIKE '%"' || ? || '"%' ESCAPE '\' ORDER BY ts DESC LIMIT ?
+	`, escapeLikePattern(playerName), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanMatchRows(rows)
+}
+
+// escapeLikePattern escapes SQLite LIKE metacharacters (\, %, _) in playerName so it matches
+// literally instead of as a wildcard pattern - otherwise a name containing "_" or "%" would
+// match other players' match history too.
+func escapeLikePattern(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(s)
+}
+
+func scanMatchRows(rows *sql.Rows) ([]*MatchRecord, error) {
+	var out []*MatchRecord
+	for rows.Next() {
+		var m MatchRecord
+		var tally, deltas, names string
+		if err := rows.Scan(&m.ID, &m.RoomCode, &m.Timestamp, &m.InsiderID, &m.SecretWord, &m.EndedByTimeout, &tally, &deltas, &names); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(tally), &m.Tally); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(deltas), &m.ScoreDeltas); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(names), &m.PlayerNames); err != nil {
+			return nil, err
+		}
+		out = append(out, &m)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
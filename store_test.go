@@ -0,0 +1,134 @@
+package main
+
+import "testing"
+
+func testStores(t *testing.T) map[string]Store {
+	sqliteStore, err := newSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("newSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { _ = sqliteStore.Close() })
+
+	return map[string]Store{
+		"memory": newInMemoryStore(),
+		"sqlite": sqliteStore,
+	}
+}
+
+func TestStoreSaveAndLoadRoomRoundTrip(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			state := &RoomState{
+				Code:     "ABCD",
+				State:    "lobby",
+				HostID:   "p1",
+				Category: "animals",
+				Players:  []PlayerState{{ID: "p1", Name: "Alice", Score: 2}},
+			}
+
+			if err := s.SaveRoom(state); err != nil {
+				t.Fatalf("SaveRoom: %v", err)
+			}
+
+			got, ok, err := s.LoadRoom("ABCD")
+			if err != nil {
+				t.Fatalf("LoadRoom: %v", err)
+			}
+			if !ok {
+				t.Fatal("expected room to be found after SaveRoom")
+			}
+			if got.HostID != "p1" || got.Category != "animals" || len(got.Players) != 1 {
+				t.Fatalf("loaded state doesn't match saved state: %+v", got)
+			}
+		})
+	}
+}
+
+func TestStoreLoadRoomMissing(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			_, ok, err := s.LoadRoom("NOPE")
+			if err != nil {
+				t.Fatalf("LoadRoom: %v", err)
+			}
+			if ok {
+				t.Fatal("expected ok=false for a room that was never saved")
+			}
+		})
+	}
+}
+
+func TestStoreDeleteRoom(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := s.SaveRoom(&RoomState{Code: "ABCD"}); err != nil {
+				t.Fatalf("SaveRoom: %v", err)
+			}
+			if err := s.DeleteRoom("ABCD"); err != nil {
+				t.Fatalf("DeleteRoom: %v", err)
+			}
+			if _, ok, _ := s.LoadRoom("ABCD"); ok {
+				t.Fatal("expected room to be gone after DeleteRoom")
+			}
+		})
+	}
+}
+
+func TestStoreAppendAndListMatches(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			match := &MatchRecord{
+				RoomCode:    "ABCD",
+				InsiderID:   "p1",
+				Tally:       map[string]int{"p1": 2},
+				ScoreDeltas: map[string]int{"p2": 1},
+				PlayerNames: map[string]string{"p1": "Alice", "p2": "Bob"},
+			}
+			if err := s.AppendMatch(match); err != nil {
+				t.Fatalf("AppendMatch: %v", err)
+			}
+
+			byRoom, err := s.ListRoomHistory("ABCD", 10)
+			if err != nil {
+				t.Fatalf("ListRoomHistory: %v", err)
+			}
+			if len(byRoom) != 1 || byRoom[0].RoomCode != "ABCD" {
+				t.Fatalf("expected one match for room ABCD, got %+v", byRoom)
+			}
+
+			byPlayer, err := s.ListRecentMatches("Bob", 10)
+			if err != nil {
+				t.Fatalf("ListRecentMatches: %v", err)
+			}
+			if len(byPlayer) != 1 {
+				t.Fatalf("expected one match for player Bob, got %+v", byPlayer)
+			}
+		})
+	}
+}
+
+func TestStoreListRecentMatchesDoesNotTreatNameAsWildcard(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			match := &MatchRecord{
+				RoomCode:    "ABCD",
+				PlayerNames: map[string]string{"p1": "Bob"},
+			}
+			if err := s.AppendMatch(match); err != nil {
+				t.Fatalf("AppendMatch: %v", err)
+			}
+
+			// "_" and "%" are LIKE wildcards - a player searching for a name containing
+			// them must not match other players' unrelated match history.
+			for _, wildcardName := range []string{"_", "%", "B_b", "B%b"} {
+				matches, err := s.ListRecentMatches(wildcardName, 10)
+				if err != nil {
+					t.Fatalf("ListRecentMatches(%q): %v", wildcardName, err)
+				}
+				if len(matches) != 0 {
+					t.Fatalf("ListRecentMatches(%q) matched Bob's history, want no match", wildcardName)
+				}
+			}
+		})
+	}
+}
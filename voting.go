@@ -0,0 +1,428 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+// VoteType discriminates the kind of ballot running on a Room (hedgewars' VoteType idea,
+// generalized past just the insider guess).
+type VoteType string
+
+const (
+	VoteInsider     VoteType = "insider"
+	VoteKick        VoteType = "kick"
+	VotePause       VoteType = "pause"
+	VoteChangeJudge VoteType = "change_judge"
+	VoteExtendTimer VoteType = "extend_timer"
+)
+
+// voteDurationSeconds is how long each VoteType stays open before it auto-resolves on
+// timeout with whatever ballots were cast so far.
+var voteDurationSeconds = map[VoteType]int{
+	VoteInsider:     VoteDurationSeconds,
+	VoteKick:        60,
+	VotePause:       30,
+	VoteChangeJudge: 45,
+	VoteExtendTimer: 30,
+}
+
+// extendTimerSecondsGranted is how much time a passed VoteExtendTimer adds to the round
+// clock when the client doesn't specify its own amount.
+const extendTimerSecondsGranted = 60
+
+// Voting is the single active ballot on a Room. start_vote/cast_vote route through here
+// regardless of type, and ActiveVote in the outgoing snapshot lets the frontend render any
+// of them uniformly (type, initiator, deadline, tally).
+type Voting struct {
+	Type        VoteType       `json:"type"`
+	InitiatorID string         `json:"initiatorId"`
+	TargetID    string         `json:"targetId,omitempty"`
+	Seconds     int            `json:"seconds,omitempty"`
+	Deadline    int64          `json:"deadline"`
+	Tally       map[string]int `json:"tally"`
+
+	ballots map[string]string // voterID -> choice (suspectID for insider, yes/no otherwise)
+	blocked map[string]bool   // insider-only: suspects tied in a prior round of this vote
+}
+
+// voteOutcome carries the side effects a resolved vote needs to apply once room.mu is
+// released (store writes, notifying/closing a kicked player's connection) - mirroring how
+// buildMatchRecord/appendMatchRecord are split across the lock boundary elsewhere.
+type voteOutcome struct {
+	record *MatchRecord
+	kicked *websocket.Conn
+}
+
+// notifyKicked tells a player they were voted out and closes their connection. Must be
+// called without room.mu held.
+func notifyKicked(conn *websocket.Conn) {
+	notify(conn, "room.kicked", newRPCError(RPCErrKicked, "คุณถูกโหวตเตะออกจากห้องโดยผู้เล่นคนอื่น"))
+	_ = conn.Close()
+}
+
+// eligibleVoterIDs returns who may cast a ballot on v, per vote type: the insider vote
+// excludes the judge and anyone blocked by a prior tie; a kick vote excludes its own target.
+func eligibleVoterIDs(room *Room, v *Voting) []string {
+	ids := make([]string, 0, len(room.Players))
+	for id := range room.Players {
+		switch v.Type {
+		case VoteInsider:
+			if id == room.JudgeID {
+				continue
+			}
+			if v.blocked != nil && v.blocked[id] {
+				continue
+			}
+		case VoteKick:
+			if id == v.TargetID {
+				continue
+			}
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func recomputeTally(v *Voting) {
+	tally := make(map[string]int)
+	for _, choice := range v.ballots {
+		tally[choice]++
+	}
+	v.Tally = tally
+}
+
+// startVote opens a new ballot on room. Only one vote may be active at a time.
+// ต้องเรียกตอนถือ room.mu อยู่แล้ว
+func startVote(room *Room, voteType VoteType, initiatorID, targetID string, seconds int) error {
+	if room.ActiveVote != nil {
+		return errors.New("มีการโหวตที่กำลังดำเนินอยู่แล้ว")
+	}
+
+	switch voteType {
+	case VoteInsider:
+		// เริ่มจาก handleGuessCorrect เท่านั้น ไม่ได้มาจาก client โดยตรง
+	case VoteKick:
+		if targetID == "" {
+			return errors.New("targetId is required")
+		}
+		if _, ok := room.Players[targetID]; !ok {
+			return errors.New("invalid targetId")
+		}
+	case VoteChangeJudge:
+		if targetID == "" {
+			return errors.New("targetId is required")
+		}
+		if _, ok := room.Players[targetID]; !ok {
+			return errors.New("invalid targetId")
+		}
+	case VotePause:
+		// ไม่มีเงื่อนไขเพิ่มเติม
+	case VoteExtendTimer:
+		if room.ExtendTimerUsed {
+			return errors.New("ขยายเวลาได้แค่ครั้งเดียวต่อรอบ")
+		}
+		if room.State != "countdown" {
+			return errors.New("ขยายเวลาได้เฉพาะตอนนับถอยหลัง")
+		}
+		if seconds <= 0 {
+			seconds = extendTimerSecondsGranted
+		}
+	default:
+		return errors.New("unknown vote type: " + string(voteType))
+	}
+
+	duration := voteDurationSeconds[voteType]
+	room.ActiveVote = &Voting{
+		Type:        voteType,
+		InitiatorID: initiatorID,
+		TargetID:    targetID,
+		Seconds:     seconds,
+		Deadline:    time.Now().Add(time.Duration(duration) * time.Second).Unix(),
+		Tally:       make(map[string]int),
+		ballots:     make(map[string]string),
+	}
+
+	// VoteInsider piggybacks on the round's own "voting" phase timer (startVoteTimer),
+	// started separately by handleGuessCorrect. Every other vote type gets its own
+	// lightweight expiry so it can run concurrently without touching the round clock.
+	if voteType != VoteInsider {
+		startVoteExpiry(room, duration)
+	}
+
+	return nil
+}
+
+// startVoteExpiry auto-resolves room's current non-insider vote if it times out before
+// reaching quorum. ต้องเรียกตอนถือ room.mu อยู่แล้ว
+func startVoteExpiry(room *Room, duration int) {
+	if room.voteCancel != nil {
+		close(room.voteCancel)
+	}
+	cancel := make(chan struct{})
+	room.voteCancel = cancel
+	pending := room.ActiveVote
+
+	go func() {
+		select {
+		case <-time.After(time.Duration(duration) * time.Second):
+			room.mu.Lock()
+			if room.ActiveVote != pending {
+				room.mu.Unlock()
+				return
+			}
+			outcome := resolveActiveVote(room)
+			room.mu.Unlock()
+
+			appendMatchRecord(outcome.record)
+			if outcome.kicked != nil {
+				notifyKicked(outcome.kicked)
+			}
+			broadcastRoom(room)
+		case <-cancel:
+		}
+	}()
+}
+
+// castVote records voterID's ballot for room's ActiveVote, validating eligibility per vote
+// type, and resolves the vote once every eligible voter has weighed in.
+// ต้องเรียกตอนถือ room.mu อยู่แล้ว
+func castVote(room *Room, voterID, choice string) (voteOutcome, error) {
+	v := room.ActiveVote
+	if v == nil {
+		return voteOutcome{}, errors.New("ไม่มีการโหวตที่กำลังดำเนินอยู่")
+	}
+	if choice == "" {
+		return voteOutcome{}, errors.New("choice is required")
+	}
+	if _, ok := room.Players[voterID]; !ok {
+		return voteOutcome{}, errors.New("player not in room")
+	}
+
+	switch v.Type {
+	case VoteInsider:
+		if voterID == room.JudgeID {
+			return voteOutcome{}, errors.New("กรรมการไม่สามารถโหวตได้")
+		}
+		if v.blocked != nil && v.blocked[voterID] {
+			return voteOutcome{}, errors.New("คุณอยู่ในกลุ่มที่ถูกสงสัย จึงไม่มีสิทธิ์โหวตรอบนี้")
+		}
+		if choice == voterID {
+			return voteOutcome{}, errors.New("ไม่สามารถโหวตตัวเองได้")
+		}
+		if _, ok := room.Players[choice]; !ok {
+			return voteOutcome{}, errors.New("invalid suspectId")
+		}
+	case VoteKick:
+		if voterID == v.TargetID {
+			return voteOutcome{}, errors.New("ไม่สามารถโหวตเตะตัวเองได้")
+		}
+		if choice != "yes" && choice != "no" {
+			return voteOutcome{}, errors.New("choice must be yes or no")
+		}
+	case VotePause, VoteChangeJudge, VoteExtendTimer:
+		if choice != "yes" && choice != "no" {
+			return voteOutcome{}, errors.New("choice must be yes or no")
+		}
+	}
+
+	v.ballots[voterID] = choice
+	recomputeTally(v)
+
+	eligible := eligibleVoterIDs(room, v)
+	if len(v.ballots) < len(eligible) || len(eligible) == 0 {
+		return voteOutcome{}, nil
+	}
+
+	// VoteInsider ใช้ round timer (timerRunning/timerCancel) ร่วมกับ ActiveVote โหวตประเภทอื่น
+	// ไม่ยุ่งกับ round timer เลย จึงหยุดเฉพาะกรณี insider
+	if v.Type == VoteInsider && room.timerRunning {
+		room.timerRunning = false
+		if room.timerCancel != nil {
+			close(room.timerCancel)
+			room.timerCancel = nil
+		}
+	}
+
+	return resolveActiveVote(room), nil
+}
+
+// resolveActiveVote dispatches room's ActiveVote to its per-type resolver (hedgewars-style),
+// replacing the old single-purpose handleTallyVotes. ต้องเรียกตอนถือ room.mu อยู่แล้ว
+func resolveActiveVote(room *Room) voteOutcome {
+	v := room.ActiveVote
+	if v == nil {
+		return voteOutcome{}
+	}
+
+	if room.voteCancel != nil {
+		close(room.voteCancel)
+		room.voteCancel = nil
+	}
+
+	switch v.Type {
+	case VoteInsider:
+		return voteOutcome{record: resolveInsiderVote(room, v)}
+	case VoteKick:
+		return voteOutcome{kicked: resolveKickVote(room, v)}
+	case VotePause:
+		resolvePauseVote(room, v)
+	case VoteChangeJudge:
+		resolveChangeJudgeVote(room, v)
+	case VoteExtendTimer:
+		resolveExtendTimerVote(room, v)
+	}
+	return voteOutcome{}
+}
+
+// resolveInsiderVote is the old handleTallyVotes logic, ported onto Voting.Tally/ballots.
+// On a tie it re-opens a fresh VoteInsider blocking the tied suspects, same as before.
+func resolveInsiderVote(room *Room, v *Voting) *MatchRecord {
+	if len(room.Players) == 0 {
+		room.ActiveVote = nil
+		return nil
+	}
+
+	beforeScores := make(map[string]int, len(room.Players))
+	playerNames := make(map[string]string, len(room.Players))
+	for id, p := range room.Players {
+		beforeScores[id] = p.Score
+		playerNames[id] = p.Name
+	}
+
+	count := v.Tally
+
+	if len(count) == 0 {
+		// ไม่มีใครโหวต → จบรอบ แบบไม่มีใครได้แต้มเพิ่ม
+		room.State = "scoreboard"
+		room.ActiveVote = nil
+		return buildMatchRecord(room, count, beforeScores, playerNames)
+	}
+
+	maxVote := -1
+	for _, c := range count {
+		if c > maxVote {
+			maxVote = c
+		}
+	}
+
+	top := []string{}
+	for id, c := range count {
+		if c == maxVote {
+			top = append(top, id)
+		}
+	}
+
+	// เสมอ → โหวตรอบใหม่ โดย "ผู้ต้องสงสัยที่คะแนนเท่ากัน" ถูก block ไม่ให้โหวต (รอบนี้ยังไม่จบ ไม่บันทึก match)
+	if len(top) > 1 {
+		blocked := make(map[string]bool, len(top))
+		for _, id := range top {
+			blocked[id] = true
+		}
+		room.State = "voting"
+		room.ActiveVote = &Voting{
+			Type:        VoteInsider,
+			InitiatorID: v.InitiatorID,
+			Tally:       make(map[string]int),
+			ballots:     make(map[string]string),
+			blocked:     blocked,
+		}
+		return nil
+	}
+
+	// มีผู้ถูกโหวตชัดเจน
+	votedID := top[0]
+	isCorrect := votedID == room.InsiderID
+
+	if isCorrect {
+		// โหวตโดน Insider → คนทั่วไปชนะ (ไม่รวม Insider / Judge)
+		for _, p := range room.Players {
+			if p.ID == room.InsiderID || p.ID == room.JudgeID {
+				continue
+			}
+			p.Score++
+		}
+	} else {
+		// โหวตผิด → Insider ชนะคนเดียว
+		if ins, ok := room.Players[room.InsiderID]; ok {
+			ins.Score += 2 // จะปรับเป็น 1 แต้มก็ได้
+		}
+	}
+
+	room.State = "scoreboard"
+	room.ActiveVote = nil
+
+	return buildMatchRecord(room, count, beforeScores, playerNames)
+}
+
+// resolveKickVote requires 2/3 of non-target players voting "yes" to kick v.TargetID.
+func resolveKickVote(room *Room, v *Voting) *websocket.Conn {
+	eligible := eligibleVoterIDs(room, v)
+	needed := (2*len(eligible) + 2) / 3 // ceil(2/3 * eligible)
+
+	room.ActiveVote = nil
+
+	if len(eligible) == 0 || v.Tally["yes"] < needed {
+		return nil
+	}
+
+	target, ok := room.Players[v.TargetID]
+	if !ok {
+		return nil
+	}
+
+	if room.JudgeID == v.TargetID {
+		room.JudgeID = ""
+	}
+	if room.InsiderID == v.TargetID {
+		room.InsiderID = ""
+	}
+	delete(room.Players, v.TargetID)
+
+	return target.Conn
+}
+
+// resolvePauseVote is a simple majority that toggles room.Paused.
+func resolvePauseVote(room *Room, v *Voting) {
+	eligible := eligibleVoterIDs(room, v)
+	room.ActiveVote = nil
+
+	if len(eligible) == 0 || v.Tally["yes"] <= v.Tally["no"] {
+		return
+	}
+	room.Paused = !room.Paused
+}
+
+// resolveChangeJudgeVote is a simple majority that hands JudgeID to v.TargetID.
+func resolveChangeJudgeVote(room *Room, v *Voting) {
+	eligible := eligibleVoterIDs(room, v)
+	room.ActiveVote = nil
+
+	if len(eligible) == 0 || v.Tally["yes"] <= v.Tally["no"] {
+		return
+	}
+	if _, ok := room.Players[v.TargetID]; !ok {
+		return
+	}
+	room.JudgeID = v.TargetID
+}
+
+// resolveExtendTimerVote is a simple majority that adds v.Seconds to the round clock, capped
+// once per round via room.ExtendTimerUsed.
+func resolveExtendTimerVote(room *Room, v *Voting) {
+	eligible := eligibleVoterIDs(room, v)
+	room.ActiveVote = nil
+
+	if len(eligible) == 0 || v.Tally["yes"] <= v.Tally["no"] {
+		return
+	}
+
+	seconds := v.Seconds
+	if seconds <= 0 {
+		seconds = extendTimerSecondsGranted
+	}
+	room.Timer += seconds
+	room.ExtendTimerUsed = true
+}
@@ -0,0 +1,144 @@
+package main
+
+import "testing"
+
+func newTestRoomWithPlayers(ids ...string) *Room {
+	room := &Room{Players: make(map[string]*Player)}
+	for _, id := range ids {
+		room.Players[id] = &Player{ID: id, Name: id}
+	}
+	return room
+}
+
+func castYesNo(room *Room, v *Voting, votes map[string]string) {
+	v.ballots = votes
+	recomputeTally(v)
+}
+
+func TestResolveKickVoteNeedsTwoThirdsMajority(t *testing.T) {
+	room := newTestRoomWithPlayers("a", "b", "c", "target")
+	v := &Voting{Type: VoteKick, TargetID: "target"}
+	// 3 eligible voters (a, b, c) - 2/3 ceil is 2, one "yes" isn't enough.
+	castYesNo(room, v, map[string]string{"a": "yes", "b": "no", "c": "no"})
+
+	if conn := resolveKickVote(room, v); conn != nil {
+		t.Fatal("expected kick vote to fail short of 2/3 majority")
+	}
+	if _, ok := room.Players["target"]; !ok {
+		t.Fatal("target should not have been removed")
+	}
+}
+
+func TestResolveKickVotePassesAndRemovesTarget(t *testing.T) {
+	room := newTestRoomWithPlayers("a", "b", "c", "target")
+	room.JudgeID = "target"
+	v := &Voting{Type: VoteKick, TargetID: "target"}
+	castYesNo(room, v, map[string]string{"a": "yes", "b": "yes", "c": "no"})
+
+	resolveKickVote(room, v)
+
+	if _, ok := room.Players["target"]; ok {
+		t.Fatal("target should have been removed from the room")
+	}
+	if room.JudgeID != "" {
+		t.Fatal("removing the judge should clear JudgeID")
+	}
+}
+
+func TestResolvePauseVoteTogglesOnMajority(t *testing.T) {
+	room := newTestRoomWithPlayers("a", "b", "c")
+	v := &Voting{Type: VotePause}
+	castYesNo(room, v, map[string]string{"a": "yes", "b": "yes", "c": "no"})
+
+	resolvePauseVote(room, v)
+
+	if !room.Paused {
+		t.Fatal("expected room.Paused to be true after a passing majority")
+	}
+}
+
+func TestResolvePauseVoteNoopOnTie(t *testing.T) {
+	room := newTestRoomWithPlayers("a", "b")
+	v := &Voting{Type: VotePause}
+	castYesNo(room, v, map[string]string{"a": "yes", "b": "no"})
+
+	resolvePauseVote(room, v)
+
+	if room.Paused {
+		t.Fatal("a tied vote must not toggle Paused")
+	}
+}
+
+func TestResolveChangeJudgeVoteRequiresValidTarget(t *testing.T) {
+	room := newTestRoomWithPlayers("a", "b", "c")
+	v := &Voting{Type: VoteChangeJudge, TargetID: "ghost"}
+	castYesNo(room, v, map[string]string{"a": "yes", "b": "yes", "c": "no"})
+
+	resolveChangeJudgeVote(room, v)
+
+	if room.JudgeID != "" {
+		t.Fatal("expected JudgeID to stay unset for a nonexistent target")
+	}
+}
+
+func TestResolveChangeJudgeVotePasses(t *testing.T) {
+	room := newTestRoomWithPlayers("a", "b", "c")
+	v := &Voting{Type: VoteChangeJudge, TargetID: "c"}
+	castYesNo(room, v, map[string]string{"a": "yes", "b": "yes"})
+
+	resolveChangeJudgeVote(room, v)
+
+	if room.JudgeID != "c" {
+		t.Fatalf("expected JudgeID to become c, got %q", room.JudgeID)
+	}
+}
+
+func TestResolveExtendTimerVoteAddsSecondsOnce(t *testing.T) {
+	room := newTestRoomWithPlayers("a", "b")
+	room.Timer = 30
+	v := &Voting{Type: VoteExtendTimer, Seconds: 15}
+	castYesNo(room, v, map[string]string{"a": "yes", "b": "yes"})
+
+	resolveExtendTimerVote(room, v)
+
+	if room.Timer != 45 {
+		t.Fatalf("expected Timer to grow by v.Seconds, got %d", room.Timer)
+	}
+	if !room.ExtendTimerUsed {
+		t.Fatal("expected ExtendTimerUsed to be set after a passing vote")
+	}
+}
+
+func TestResolveInsiderVoteTieReopensVoteBlockingTiedSuspects(t *testing.T) {
+	room := newTestRoomWithPlayers("a", "b", "c", "d")
+	room.InsiderID = "c"
+	v := &Voting{Type: VoteInsider, Tally: map[string]int{"c": 1, "d": 1}}
+
+	record := resolveInsiderVote(room, v)
+
+	if record != nil {
+		t.Fatal("a tie must not produce a match record")
+	}
+	if room.ActiveVote == nil || len(room.ActiveVote.blocked) != 2 {
+		t.Fatal("expected a fresh VoteInsider blocking both tied suspects")
+	}
+}
+
+func TestResolveInsiderVoteCorrectGuessAwardsNonInsiders(t *testing.T) {
+	room := newTestRoomWithPlayers("insider", "judge", "p1", "p2")
+	room.InsiderID = "insider"
+	room.JudgeID = "judge"
+	v := &Voting{Type: VoteInsider, Tally: map[string]int{"insider": 3}}
+
+	resolveInsiderVote(room, v)
+
+	if room.Players["p1"].Score != 1 || room.Players["p2"].Score != 1 {
+		t.Fatal("expected every non-insider, non-judge player to gain a point")
+	}
+	if room.Players["insider"].Score != 0 || room.Players["judge"].Score != 0 {
+		t.Fatal("insider and judge must not gain points on a correct guess")
+	}
+	if room.ActiveVote != nil {
+		t.Fatal("expected ActiveVote to be cleared once the round resolves")
+	}
+}
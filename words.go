@@ -0,0 +1,208 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// wordsDir คือโฟลเดอร์ที่เก็บไฟล์ deck คำปริศนา (*.json) ที่โหลดตอนสตาร์ทเซิร์ฟเวอร์
+const wordsDir = "words"
+
+// WordDeck คือชุดคำปริศนาหนึ่งหมวด เช่น "animals", "food"
+type WordDeck struct {
+	Category string   `json:"category"`
+	Words    []string `json:"words"`
+}
+
+var (
+	wordDecksMu sync.RWMutex
+	wordDecks   = make(map[string]*WordDeck)
+)
+
+func init() {
+	if err := loadWordDecks(wordsDir); err != nil {
+		log.Println("[words] failed to load decks from", wordsDir, "-", err)
+	}
+}
+
+// loadWordDecks อ่านไฟล์ *.json ทุกไฟล์ใน dir แล้วแทนที่ registry ทั้งหมดด้วย deck ที่โหลดได้
+// ใช้ตอนสตาร์ทเซิร์ฟเวอร์ และถูกเรียกซ้ำได้จาก admin reload endpoint
+func loadWordDecks(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	decks := make(map[string]*WordDeck)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			log.Println("[words] skip", entry.Name(), "-", err)
+			continue
+		}
+
+		var deck WordDeck
+		if err := json.Unmarshal(data, &deck); err != nil {
+			log.Println("[words] skip", entry.Name(), "- invalid json:", err)
+			continue
+		}
+
+		if deck.Category == "" {
+			deck.Category = strings.TrimSuffix(entry.Name(), ".json")
+		}
+		if len(deck.Words) == 0 {
+			log.Println("[words] skip", entry.Name(), "- deck has no words")
+			continue
+		}
+
+		decks[deck.Category] = &deck
+	}
+
+	wordDecksMu.Lock()
+	wordDecks = decks
+	wordDecksMu.Unlock()
+
+	log.Printf("[words] loaded %d deck(s) from %s\n", len(decks), dir)
+	return nil
+}
+
+// getWordDeck คืน deck ของหมวดนั้น ๆ จาก registry กลาง (ไม่รวม custom deck รายห้อง)
+func getWordDeck(category string) (*WordDeck, bool) {
+	wordDecksMu.RLock()
+	defer wordDecksMu.RUnlock()
+	deck, ok := wordDecks[category]
+	return deck, ok
+}
+
+// listWordDecks คืนรายชื่อหมวดคำทั้งหมดที่โหลดไว้ ใช้ตอบ admin endpoint
+func listWordDecks() []string {
+	wordDecksMu.RLock()
+	defer wordDecksMu.RUnlock()
+
+	categories := make([]string, 0, len(wordDecks))
+	for category := range wordDecks {
+		categories = append(categories, category)
+	}
+	return categories
+}
+
+// pickWordForRoom สุ่มคำใหม่จาก deck ที่ห้องเลือกไว้ (room.Category) โดยเว้นคำที่เคยใช้ไปแล้วในห้องนี้
+// ถ้าคำในหมวดถูกใช้จนหมดแล้วจะวนกลับมาใช้ใหม่ทั้งหมด ต้องเรียกตอนถือ room.mu อยู่แล้ว
+func pickWordForRoom(room *Room) (string, error) {
+	if room.Category == "" {
+		return "", errors.New("กรุณาเลือกหมวดคำก่อนเริ่มเกม")
+	}
+
+	deck, ok := getWordDeck(room.Category)
+	if !ok {
+		deck, ok = room.CustomDecks[room.Category]
+	}
+	if !ok || len(deck.Words) == 0 {
+		return "", errors.New("ไม่พบหมวดคำที่เลือกไว้")
+	}
+
+	if room.UsedWords == nil {
+		room.UsedWords = make(map[string]map[string]bool)
+	}
+	used := room.UsedWords[room.Category]
+
+	available := make([]string, 0, len(deck.Words))
+	for _, w := range deck.Words {
+		if used[w] {
+			continue
+		}
+		available = append(available, w)
+	}
+
+	if len(available) == 0 {
+		used = make(map[string]bool)
+		available = append(available, deck.Words...)
+	}
+
+	word := available[rand.Intn(len(available))]
+
+	if used == nil {
+		used = make(map[string]bool)
+	}
+	used[word] = true
+	room.UsedWords[room.Category] = used
+
+	return word, nil
+}
+
+// isAdminAuthorized เช็ค header X-Admin-Token เทียบกับ ADMIN_TOKEN ใน env
+// ถ้าไม่ได้ตั้ง ADMIN_TOKEN ไว้ ถือว่าปิดใช้งาน admin endpoint ทั้งหมด
+func isAdminAuthorized(c *fiber.Ctx) bool {
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	if adminToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.Get("X-Admin-Token")), []byte(adminToken)) == 1
+}
+
+// reloadWordDecksHandler คือ admin endpoint สำหรับ hot-reload deck จาก wordsDir
+// โดยไม่ต้อง restart เซิร์ฟเวอร์ เช่นตอนเพิ่ม/แก้ไฟล์ deck ใหม่
+func reloadWordDecksHandler(c *fiber.Ctx) error {
+	if !isAdminAuthorized(c) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	if err := loadWordDecks(wordsDir); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"categories": listWordDecks()})
+}
+
+// uploadRoomDeckHandler ให้ host อัปโหลด deck คำของตัวเองแบบ scoped เฉพาะห้องนั้น ๆ
+// deck ที่อัปโหลดใช้ได้เหมือน deck กลางผ่าน set_category แต่ไม่ปนกับห้องอื่น
+// ต้องแนบ session token (header X-Session-Token เดียวกับที่ใช้ reconnect websocket) ของ host ห้องนั้น
+func uploadRoomDeckHandler(c *fiber.Ctx) error {
+	roomCode := c.Params("code")
+	room, err := getOrCreateRoom(roomCode, false)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "room not found"})
+	}
+
+	tokRoom, tokPlayerID, ok := parseSessionToken(c.Get("X-Session-Token"))
+	if !ok || tokRoom != roomCode {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	room.mu.Lock()
+	isHost := tokPlayerID == room.HostID
+	room.mu.Unlock()
+	if !isHost {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "only the room host can upload a deck"})
+	}
+
+	var deck WordDeck
+	if err := c.BodyParser(&deck); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid deck payload"})
+	}
+	if deck.Category == "" || len(deck.Words) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "category and words are required"})
+	}
+
+	room.mu.Lock()
+	if room.CustomDecks == nil {
+		room.CustomDecks = make(map[string]*WordDeck)
+	}
+	room.CustomDecks[deck.Category] = &deck
+	room.mu.Unlock()
+
+	return c.JSON(fiber.Map{"category": deck.Category, "words": len(deck.Words)})
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func newTestRoomWithDeck(words []string) *Room {
+	return &Room{
+		Code:     "TEST",
+		Category: "zzz-test-category",
+		CustomDecks: map[string]*WordDeck{
+			"zzz-test-category": {Category: "zzz-test-category", Words: words},
+		},
+		UsedWords: make(map[string]map[string]bool),
+	}
+}
+
+func TestPickWordForRoomRequiresCategory(t *testing.T) {
+	room := newTestRoomWithDeck([]string{"cat", "dog"})
+	room.Category = ""
+
+	if _, err := pickWordForRoom(room); err == nil {
+		t.Fatal("expected an error when no category is selected")
+	}
+}
+
+func TestPickWordForRoomCyclesWithoutRepeatsThenResets(t *testing.T) {
+	room := newTestRoomWithDeck([]string{"cat", "dog"})
+
+	seen := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		word, err := pickWordForRoom(room)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if seen[word] {
+			t.Fatalf("word %q repeated before the deck was exhausted", word)
+		}
+		seen[word] = true
+	}
+
+	if len(seen) != 2 {
+		t.Fatalf("expected both words to have been used, got %v", seen)
+	}
+
+	// Deck is now exhausted - the next pick must recycle the full word list rather
+	// than erroring or returning nothing.
+	word, err := pickWordForRoom(room)
+	if err != nil {
+		t.Fatalf("unexpected error after deck exhaustion: %v", err)
+	}
+	if word != "cat" && word != "dog" {
+		t.Fatalf("expected a recycled word from the deck, got %q", word)
+	}
+}
+
+func TestPickWordForRoomUnknownCategory(t *testing.T) {
+	room := newTestRoomWithDeck([]string{"cat"})
+	room.Category = "does-not-exist"
+
+	if _, err := pickWordForRoom(room); err == nil {
+		t.Fatal("expected an error for an unknown category")
+	}
+}